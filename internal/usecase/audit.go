@@ -0,0 +1,212 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	auditDecisionSigned          = "signed"
+	auditDecisionRejected        = "rejected"
+	auditDecisionPendingApproval = "pending_approval"
+	auditDecisionTimeLocked      = "time_locked"
+
+	// audit records are bucketed by UTC day so time range queries only list the matching days
+	auditDayLayout = "2006-01-02"
+
+	// auditCoalesceWindow is how long the rate-limited rejections of a key-manager and entity
+	// share a single audit record
+	auditCoalesceWindow = time.Minute
+)
+
+// AuditRecord is a signing decision persisted in plugin storage.
+type AuditRecord struct {
+	ID          string    `json:"id"`
+	Time        time.Time `json:"time"`
+	Operation   string    `json:"operation"`
+	EntityID    string    `json:"entity_id"`
+	ServiceName string    `json:"service_name"`
+	Address     string    `json:"address"`
+	ChainID     string    `json:"chain_id,omitempty"`
+	Nonce       *uint64   `json:"nonce,omitempty"`
+	To          string    `json:"to,omitempty"`
+	Value       string    `json:"value,omitempty"`
+	Selector    string    `json:"selector,omitempty"`
+	Hash        string    `json:"hash,omitempty"`
+	TxHash      string    `json:"tx_hash,omitempty"`
+	Decision    string    `json:"decision"`
+	Reason      string    `json:"reason,omitempty"`
+}
+
+// rateLimitedAudit counts the rate-limited rejections coalesced since the last audit record.
+type rateLimitedAudit struct {
+	start     time.Time
+	coalesced int
+	last      AuditRecord
+}
+
+func (w *rateLimitedAudit) reason(reason string) string {
+	return fmt.Sprintf("%s (%d more rate-limited requests since %s)",
+		reason, w.coalesced, w.start.UTC().Format(time.RFC3339))
+}
+
+func newAuditRecord(req *logical.Request, operation string) *AuditRecord {
+	return &AuditRecord{
+		Operation: operation,
+		EntityID:  req.EntityID,
+	}
+}
+
+func (r *AuditRecord) setTx(tx *types.Transaction, chainID *big.Int) {
+	nonce := tx.Nonce()
+	r.Nonce = &nonce
+	r.ChainID = chainID.String()
+	r.Value = tx.Value().String()
+	if tx.To() != nil {
+		r.To = tx.To().Hex()
+	}
+	if len(tx.Data()) >= 4 {
+		r.Selector = hexutil.Encode(tx.Data()[:4])
+	}
+}
+
+// recordAudit persists the outcome of a signing request. Failing to persist the record is logged
+// but does not change the outcome of the request.
+func (b *Backend) recordAudit(
+	ctx context.Context,
+	req *logical.Request,
+	record *AuditRecord,
+	resp *logical.Response,
+	err error,
+) {
+	switch {
+	case err != nil:
+		record.Decision = auditDecisionRejected
+		record.Reason = err.Error()
+	case resp != nil && resp.Data["approvalId"] != nil:
+		record.Decision = auditDecisionPendingApproval
+		record.Reason = fmt.Sprintf("approval %s", resp.Data["approvalId"])
+	case resp != nil && resp.Data["timelockId"] != nil:
+		record.Decision = auditDecisionTimeLocked
+		record.Reason = fmt.Sprintf("timelock %s", resp.Data["timelockId"])
+	default:
+		record.Decision = auditDecisionSigned
+		if resp != nil {
+			if txHash, ok := resp.Data["txHash"].(string); ok {
+				record.TxHash = txHash
+			}
+			if resp.Data["replayed"] == true {
+				record.Reason = "replayed stored result"
+			}
+		}
+	}
+
+	// a flood of rate-limited requests must not turn into a flood of storage writes and webhook
+	// deliveries, they share one audit record per key-manager and entity and window
+	if isRateLimited(err) && !b.coalesceRateLimited(record) {
+		return
+	}
+
+	b.writeAuditRecord(ctx, req.Storage, record)
+}
+
+func (b *Backend) writeAuditRecord(ctx context.Context, storage logical.Storage, record *AuditRecord) {
+	if err := b.saveAuditRecord(ctx, storage, record); err != nil {
+		b.Logger().Error("Failed to save the audit record", "operation", record.Operation,
+			"service_name", record.ServiceName, "error", err)
+	}
+
+	if err := b.enqueueWebhooks(ctx, storage, record); err != nil {
+		b.Logger().Error("Failed to queue the webhook event", "id", record.ID, "error", err)
+	}
+}
+
+func (b *Backend) saveAuditRecord(ctx context.Context, storage logical.Storage, record *AuditRecord) error {
+	suffix, err := uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+
+	record.Time = time.Now().UTC()
+	record.ID = fmt.Sprintf("%019d-%s", record.Time.UnixNano(), suffix[:8])
+
+	entry, _ := logical.StorageEntryJSON(auditPath(record.ID, record.Time), record)
+	return storage.Put(ctx, entry)
+}
+
+// coalesceRateLimited reports whether the rate-limited rejection gets its own audit record, which
+// only the first one of a key-manager and entity in every auditCoalesceWindow does. The others are
+// counted and reported by the next record, or by flushRateLimitedAudits once the window is over.
+func (b *Backend) coalesceRateLimited(record *AuditRecord) bool {
+	key := record.ServiceName + "/" + record.EntityID
+	now := time.Now()
+
+	b.rateLimitedAuditsLock.Lock()
+	defer b.rateLimitedAuditsLock.Unlock()
+
+	if b.rateLimitedAudits == nil {
+		b.rateLimitedAudits = make(map[string]*rateLimitedAudit)
+	}
+
+	window, ok := b.rateLimitedAudits[key]
+	if ok && now.Sub(window.start) < auditCoalesceWindow {
+		window.last = *record
+		window.coalesced++
+		return false
+	}
+	if ok && window.coalesced > 0 {
+		record.Reason = window.reason(record.Reason)
+	}
+	b.rateLimitedAudits[key] = &rateLimitedAudit{start: now}
+	return true
+}
+
+// flushRateLimitedAudits writes a record for the rate-limited rejections coalesced in the windows
+// that are over.
+func (b *Backend) flushRateLimitedAudits(ctx context.Context, storage logical.Storage, now time.Time) {
+	var records []*AuditRecord
+
+	b.rateLimitedAuditsLock.Lock()
+	for key, window := range b.rateLimitedAudits {
+		if now.Sub(window.start) < auditCoalesceWindow {
+			continue
+		}
+		if window.coalesced > 0 {
+			record := window.last
+			record.Reason = window.reason(record.Reason)
+			records = append(records, &record)
+		}
+		delete(b.rateLimitedAudits, key)
+	}
+	b.rateLimitedAuditsLock.Unlock()
+
+	for _, record := range records {
+		b.writeAuditRecord(ctx, storage, record)
+	}
+}
+
+func auditPath(id string, t time.Time) string {
+	return fmt.Sprintf("audit/%s/%s", t.UTC().Format(auditDayLayout), id)
+}
+
+// auditTime recovers the record time from its ID.
+func auditTime(id string) (time.Time, error) {
+	nanos, _, ok := strings.Cut(id, "-")
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid audit record id %q", id)
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid audit record id %q", id)
+	}
+	return time.Unix(0, n).UTC(), nil
+}
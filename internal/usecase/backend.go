@@ -3,14 +3,42 @@ package usecase
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
+	"github.com/google/cel-go/cel"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/time/rate"
 )
 
 // Backend implements the Backend for this plugin
 type Backend struct {
 	*framework.Backend
+
+	policyEnvOnce sync.Once
+	celEnv        *cel.Env
+	celEnvErr     error
+	policyCache   policyCache
+
+	queueLock sync.Mutex
+	nonceLock sync.Mutex
+
+	// webhookLock guards the outbox deliveries that are being delivered
+	webhookLock      sync.Mutex
+	webhooksInFlight map[string]bool
+
+	wrappingKeyLock sync.Mutex
+
+	// keyManagerLock serializes every read-modify-write of a key-manager, so that concurrent
+	// updates, such as pool refills and signature counts, do not drop each other's changes
+	keyManagerLock sync.Mutex
+
+	limitersLock sync.Mutex
+	limiters     map[string]*rate.Limiter
+
+	rateLimitedAuditsLock sync.Mutex
+	rateLimitedAudits     map[string]*rateLimitedAudit
 }
 
 // Factory returns the backend
@@ -33,9 +61,15 @@ func backend() *Backend {
 		PathsSpecial: &logical.Paths{
 			SealWrapStorage: []string{
 				"key-managers/",
+				configPath,
+				wrappingKeyPath,
+				backupSigningKeyPath,
 			},
 		},
-		Secrets:     []*framework.Secret{},
+		PeriodicFunc: b.periodicFunc,
+		Secrets: []*framework.Secret{
+			secretSessionKey(&b),
+		},
 		BackendType: logical.TypeLogical,
 	}
 	return &b
@@ -50,3 +84,21 @@ func (b *Backend) pathExistenceCheck(ctx context.Context, req *logical.Request,
 
 	return out != nil, nil
 }
+
+// periodicFunc writes the coalesced audit records of rate-limited requests, prunes expired approval
+// requests, retries the webhook deliveries that are due and refills the key pools.
+func (b *Backend) periodicFunc(ctx context.Context, req *logical.Request) error {
+	b.pruneLimiters()
+
+	if !b.WriteSafeReplicationState() {
+		return nil
+	}
+	b.flushRateLimitedAudits(ctx, req.Storage, time.Now())
+	if err := b.pruneExpiredApprovals(ctx, req.Storage, time.Now()); err != nil {
+		return err
+	}
+	if err := b.flushWebhooks(ctx, req.Storage); err != nil {
+		return err
+	}
+	return b.refillPools(ctx, req.Storage)
+}
@@ -0,0 +1,205 @@
+package usecase
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const backupVersion = 2
+
+// backupSigningKeyPath stores the RSA key that signs the backups of the mount. It is kept apart from
+// the wrapping key, which only decrypts.
+const backupSigningKeyPath = "backup_signing_key"
+
+// backupAAD binds the ciphertext to this plugin and backup format.
+var backupAAD = []byte("vault-eth-signer/backup/v2")
+
+// Backup is the content of a key-manager backup: the key-manager with its key material and the
+// signing policies attached to it.
+type Backup struct {
+	KeyManager *KeyManager `json:"key_manager"`
+	Policies   []*Policy   `json:"policies"`
+}
+
+// sealedBackup is a backup encrypted with AES-256-GCM under a one-time key that is wrapped to the
+// wrapping key of the mount the backup is meant for. It is signed (RSA-PSS) with the backup signing
+// key of the mount that made it, so a restore only accepts backups from mounts it trusts.
+type sealedBackup struct {
+	Version    int    `json:"version"`
+	WrappedKey []byte `json:"wrapped_key"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+	Signature  []byte `json:"signature,omitempty"`
+}
+
+// digest is the hash of every field of the backup but its signature.
+func (s sealedBackup) digest() ([]byte, error) {
+	s.Signature = nil
+	raw, err := json.Marshal(&s)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(raw)
+	return sum[:], nil
+}
+
+func sealBackup(backup *Backup, wrappingKey *rsa.PublicKey, signingKey *rsa.PrivateKey) (string, error) {
+	plaintext, err := json.Marshal(backup)
+	if err != nil {
+		return "", err
+	}
+	defer zeroBytes(plaintext)
+
+	key := make([]byte, 32)
+	if _, err = io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	defer zeroBytes(key)
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, wrappingKey, key, backupAAD)
+	if err != nil {
+		return "", err
+	}
+
+	sealed := &sealedBackup{
+		Version:    backupVersion,
+		WrappedKey: wrappedKey,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plaintext, backupAAD),
+	}
+	digest, err := sealed.digest()
+	if err != nil {
+		return "", err
+	}
+	if sealed.Signature, err = rsa.SignPSS(rand.Reader, signingKey, crypto.SHA256, digest, nil); err != nil {
+		return "", err
+	}
+
+	raw, err := json.Marshal(sealed)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+// openBackup checks that the backup was signed by one of the trusted keys before decrypting it
+// with the wrapping key.
+func openBackup(blob string, wrappingKey *rsa.PrivateKey, trustedKeys []*rsa.PublicKey) (*Backup, error) {
+	raw, err := base64.StdEncoding.DecodeString(blob)
+	if err != nil {
+		return nil, errors.New("backup must be base64 encoded")
+	}
+
+	var sealed sealedBackup
+	if err = json.Unmarshal(raw, &sealed); err != nil {
+		return nil, fmt.Errorf("invalid backup: %w", err)
+	}
+	if sealed.Version != backupVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", sealed.Version)
+	}
+
+	digest, err := sealed.digest()
+	if err != nil {
+		return nil, err
+	}
+	trusted := false
+	for _, key := range trustedKeys {
+		if rsa.VerifyPSS(key, crypto.SHA256, digest, sealed.Signature, nil) == nil {
+			trusted = true
+			break
+		}
+	}
+	if !trusted {
+		return nil, errors.New("backup is not signed by a trusted mount")
+	}
+
+	key, err := rsa.DecryptOAEP(sha256.New(), nil, wrappingKey, sealed.WrappedKey, backupAAD)
+	if err != nil {
+		return nil, errors.New("backup was not encrypted for this mount")
+	}
+	defer zeroBytes(key)
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed.Nonce) != gcm.NonceSize() {
+		return nil, errors.New("invalid backup nonce")
+	}
+
+	plaintext, err := gcm.Open(nil, sealed.Nonce, sealed.Ciphertext, backupAAD)
+	if err != nil {
+		return nil, errors.New("backup failed authentication")
+	}
+	defer zeroBytes(plaintext)
+
+	var backup Backup
+	if err = json.Unmarshal(plaintext, &backup); err != nil {
+		return nil, fmt.Errorf("invalid backup: %w", err)
+	}
+	if backup.KeyManager == nil {
+		return nil, errors.New("invalid backup: no keyManager")
+	}
+	return &backup, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func parsePublicKeyPEM(input string) (*rsa.PublicKey, error) {
+	return parseNamedPublicKeyPEM("wrappingKey", input)
+}
+
+func parsePublicKeysPEM(field string, input []string) ([]*rsa.PublicKey, error) {
+	keys := make([]*rsa.PublicKey, 0, len(input))
+	for _, raw := range input {
+		key, err := parseNamedPublicKeyPEM(field, raw)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func parseNamedPublicKeyPEM(field, input string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(input))
+	if block == nil {
+		return nil, fmt.Errorf("%s must be a PEM encoded public key", field)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", field, err)
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s must be an RSA public key", field)
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,84 @@
+package usecase
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// derivationInfoPrefix separates the HKDF derived keys from any other use of the seed
+	derivationInfoPrefix = "vault-eth-signer/derive/"
+
+	maxDerivationIDLength = 256
+)
+
+// deriveIDKey derives the private key of an external identifier from the seed with HKDF-SHA256.
+// The same seed and identifier always result in the same key.
+func deriveIDKey(seed []byte, derivationID string) (*ecdsa.PrivateKey, error) {
+	reader := hkdf.New(sha256.New, seed, nil, []byte(derivationInfoPrefix+derivationID))
+
+	// a candidate that is not a valid secp256k1 scalar is skipped for the next HKDF output
+	buf := make([]byte, 32)
+	for {
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		if privateKey, err := crypto.ToECDSA(buf); err == nil {
+			return privateKey, nil
+		}
+	}
+}
+
+// derivedKeyPair returns the key pair the key-manager derives for the identifier. Derived key
+// pairs are not stored, they are derived again whenever they are used.
+func (k *KeyManager) derivedKeyPair(derivationID string) (*KeyPair, error) {
+	if derivationID == "" || len(derivationID) > maxDerivationIDLength {
+		return nil, fmt.Errorf("derivationId must be 1 to %d characters long", maxDerivationIDLength)
+	}
+
+	seed := common.FromHex(k.Seed)
+	if len(seed) == 0 {
+		return nil, fmt.Errorf("keyManager %s has no seed to derive keys from, create it with hd=true", k.ServiceName)
+	}
+
+	privateKey, err := deriveIDKey(seed, derivationID)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(privateKey)
+
+	return &KeyPair{
+		PublicKey:    common.Bytes2Hex(crypto.FromECDSAPub(&privateKey.PublicKey)),
+		Address:      crypto.PubkeyToAddress(privateKey.PublicKey).Hex(),
+		DerivationID: derivationID,
+	}, nil
+}
+
+// resolveKeyPair returns the key pair that signs for the address, or the derived key pair when a
+// derivation ID is given. An address given together with a derivation ID has to match it.
+func (k *KeyManager) resolveKeyPair(address, derivationID string) (*KeyPair, error) {
+	if derivationID == "" {
+		keyPair := k.keyPair(address)
+		if keyPair == nil {
+			return nil, errors.New("no private key for the input address")
+		}
+		return keyPair, nil
+	}
+
+	keyPair, err := k.derivedKeyPair(derivationID)
+	if err != nil {
+		return nil, err
+	}
+	if address != "" && !strings.EqualFold(address, keyPair.Address) {
+		return nil, fmt.Errorf("address %s does not belong to derivationId %s", address, derivationID)
+	}
+	return keyPair, nil
+}
@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// EscrowSecret is the key material of a key-manager that is split into escrow shares, with the
+// settings that restrict how its keys sign.
+type EscrowSecret struct {
+	ServiceName string      `json:"service_name"`
+	Seed        string      `json:"seed,omitempty"`
+	NextIndex   uint32      `json:"next_index,omitempty"`
+	KeyPairs    []*KeyPair  `json:"key_pairs"`
+	Settings    *KeyManager `json:"settings"`
+}
+
+// settings returns a copy of the key-manager without its key material.
+func (k *KeyManager) settings() *KeyManager {
+	settings := *k
+	settings.ServiceName = ""
+	settings.KeyPairs = nil
+	settings.Seed = ""
+	settings.NextIndex = 0
+	return &settings
+}
+
+// parsePGPKey reads a PGP public key, armored or base64 encoded binary as in Vault's pgp_keys.
+func parsePGPKey(input string) (*openpgp.Entity, error) {
+	input = strings.TrimSpace(input)
+
+	var reader *packet.Reader
+	if strings.HasPrefix(input, "-----BEGIN") {
+		block, err := armor.Decode(strings.NewReader(input))
+		if err != nil {
+			return nil, fmt.Errorf("invalid PGP key: %w", err)
+		}
+		reader = packet.NewReader(block.Body)
+	} else {
+		raw, err := base64.StdEncoding.DecodeString(input)
+		if err != nil {
+			return nil, fmt.Errorf("PGP keys must be armored or base64 encoded")
+		}
+		reader = packet.NewReader(bytes.NewReader(raw))
+	}
+
+	entity, err := openpgp.ReadEntity(reader)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PGP key: %w", err)
+	}
+	return entity, nil
+}
+
+// encryptShare encrypts the base64 encoded share to the PGP key and returns the base64 encoded
+// binary message, which the recipient decrypts with e.g. `base64 -d | gpg -d`.
+func encryptShare(share []byte, entity *openpgp.Entity) (string, error) {
+	var buf bytes.Buffer
+	w, err := openpgp.Encrypt(&buf, []*openpgp.Entity{entity}, nil, &openpgp.FileHints{IsBinary: false}, nil)
+	if err != nil {
+		return "", err
+	}
+	if _, err = w.Write([]byte(base64.StdEncoding.EncodeToString(share))); err != nil {
+		return "", err
+	}
+	if err = w.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func pgpFingerprint(entity *openpgp.Entity) string {
+	return hex.EncodeToString(entity.PrimaryKey.Fingerprint)
+}
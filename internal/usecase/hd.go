@@ -0,0 +1,174 @@
+package usecase
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+const (
+	// hardenedOffset marks a BIP-32 child index as hardened
+	hardenedOffset = 0x80000000
+
+	// bip44PathFormat is the BIP-44 path of the i-th external Ethereum address of the first account
+	bip44PathFormat = "m/44'/60'/0'/0/%d"
+
+	mnemonicEntropyBits = 256
+)
+
+var errInvalidChildKey = errors.New("derived an invalid child key")
+
+// extendedKey is a BIP-32 extended private key.
+type extendedKey struct {
+	key       []byte
+	chainCode []byte
+}
+
+// newMnemonic generates a 24 word BIP-39 mnemonic.
+func newMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(mnemonicEntropyBits)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// seedFromMnemonic validates the BIP-39 mnemonic and returns its seed.
+func seedFromMnemonic(mnemonic, passphrase string) ([]byte, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(strings.Join(strings.Fields(mnemonic), " "), passphrase)
+	if err != nil {
+		return nil, errors.New("invalid BIP-39 mnemonic")
+	}
+	return seed, nil
+}
+
+func masterKey(seed []byte) (*extendedKey, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	k := new(big.Int).SetBytes(sum[:32])
+	if k.Sign() == 0 || k.Cmp(crypto.S256().Params().N) >= 0 {
+		return nil, errInvalidChildKey
+	}
+	return &extendedKey{key: sum[:32], chainCode: sum[32:]}, nil
+}
+
+// child derives the private child key at the index as specified by BIP-32.
+func (e *extendedKey) child(index uint32) (*extendedKey, error) {
+	var data []byte
+	if index >= hardenedOffset {
+		data = append([]byte{0x00}, e.key...)
+	} else {
+		privateKey, err := crypto.ToECDSA(e.key)
+		if err != nil {
+			return nil, err
+		}
+		data = crypto.CompressPubkey(&privateKey.PublicKey)
+		zeroKey(privateKey)
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	mac := hmac.New(sha512.New, e.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	n := crypto.S256().Params().N
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, errInvalidChildKey
+	}
+
+	k := il.Add(il, new(big.Int).SetBytes(e.key))
+	k.Mod(k, n)
+	if k.Sign() == 0 {
+		return nil, errInvalidChildKey
+	}
+	return &extendedKey{key: math.PaddedBigBytes(k, 32), chainCode: sum[32:]}, nil
+}
+
+// parseDerivationPath parses a path such as m/44'/60'/0'/0/1 into its child indexes.
+func parseDerivationPath(path string) ([]uint32, error) {
+	parts := strings.Split(path, "/")
+	if len(parts) == 0 || parts[0] != "m" {
+		return nil, fmt.Errorf("invalid derivation path %q", path)
+	}
+
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		var offset uint32
+		if strings.HasSuffix(part, "'") {
+			part = strings.TrimSuffix(part, "'")
+			offset = hardenedOffset
+		}
+		index, err := strconv.ParseUint(part, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("invalid derivation path %q", path)
+		}
+		indexes = append(indexes, uint32(index)+offset)
+	}
+	return indexes, nil
+}
+
+// deriveKey derives the private key at the derivation path from the seed.
+func deriveKey(seed []byte, path string) (*ecdsa.PrivateKey, error) {
+	indexes, err := parseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := masterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range indexes {
+		if key, err = key.child(index); err != nil {
+			return nil, err
+		}
+	}
+	return crypto.ToECDSA(key.key)
+}
+
+// newHDKeyPair derives the key pair at the derivation path. Only the path is stored, the private
+// key is derived again from the seed of the key-manager whenever it signs.
+func newHDKeyPair(seed []byte, path string) (*KeyPair, error) {
+	privateKey, err := deriveKey(seed, path)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(privateKey)
+
+	return &KeyPair{
+		PublicKey:      common.Bytes2Hex(crypto.FromECDSAPub(&privateKey.PublicKey)),
+		Address:        crypto.PubkeyToAddress(privateKey.PublicKey).Hex(),
+		DerivationPath: path,
+	}, nil
+}
+
+// privateKey returns the private key of the key pair, deriving it from the seed for HD and
+// derived key pairs. Callers zero the key once they are done with it.
+func (k *KeyManager) privateKey(keyPair *KeyPair) (*ecdsa.PrivateKey, error) {
+	if keyPair.DerivationPath == "" && keyPair.DerivationID == "" {
+		return crypto.HexToECDSA(keyPair.PrivateKey)
+	}
+
+	seed := common.FromHex(k.Seed)
+	if len(seed) == 0 {
+		return nil, fmt.Errorf("keyManager %s has no seed to derive keys from", k.ServiceName)
+	}
+	if keyPair.DerivationID != "" {
+		return deriveIDKey(seed, keyPair.DerivationID)
+	}
+	return deriveKey(seed, keyPair.DerivationPath)
+}
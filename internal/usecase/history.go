@@ -0,0 +1,197 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+var idempotencyKeyRegex = regexp.MustCompile(`^[\w.-]{1,128}$`)
+
+// SignedTxRecord is a transaction signed for an address, kept so that an identical request is
+// answered with the stored result instead of a fresh signature.
+type SignedTxRecord struct {
+	SignedTransaction
+	SigHash  string    `json:"sig_hash"`
+	Nonce    uint64    `json:"nonce"`
+	ChainID  string    `json:"chain_id"`
+	SignedAt time.Time `json:"signed_at"`
+}
+
+// IdempotencyRecord is the response of a request made with a client-supplied idempotency key.
+type IdempotencyRecord struct {
+	Key         string                 `json:"key"`
+	Fingerprint string                 `json:"fingerprint"`
+	Response    map[string]interface{} `json:"response"`
+	CreatedAt   time.Time              `json:"created_at"`
+}
+
+func historyPath(address, sigHash string) string {
+	return fmt.Sprintf("history/%s/%s", strings.ToLower(address), sigHash)
+}
+
+func idempotencyPath(serviceName, key string) string {
+	return fmt.Sprintf("idempotency/%s/%s", serviceName, key)
+}
+
+// sigHash returns the hash the signer signs for the transaction, which identifies the unsigned
+// transaction including its chain ID.
+func sigHash(tx *types.Transaction, chainID *big.Int) string {
+	signer, _ := signerForTx(tx, chainID)
+	return signer.Hash(tx).Hex()
+}
+
+func (b *Backend) retrieveSignedTx(
+	ctx context.Context,
+	req *logical.Request,
+	address string,
+	hash string,
+) (*SignedTxRecord, error) {
+	path := historyPath(address, hash)
+	entry, err := req.Storage.Get(ctx, path)
+	if err != nil {
+		b.Logger().Error("Failed to retrieve the signed transaction", "path", path, "error", err)
+		return nil, err
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	var record SignedTxRecord
+	if err = entry.DecodeJSON(&record); err != nil {
+		b.Logger().Error("Failed to decode signed transaction", "path", path, "error", err)
+		return nil, err
+	}
+	return &record, nil
+}
+
+// signAndRecordTransaction returns the stored result when the identical transaction was already
+// signed for the address, and otherwise signs it and adds it to the history of the address. A
+// different transaction for an already signed nonce is only signed as a replacement.
+func (b *Backend) signAndRecordTransaction(
+	ctx context.Context,
+	req *logical.Request,
+	keyManager *KeyManager,
+	keyPair *KeyPair,
+	tx *types.Transaction,
+	chainID *big.Int,
+	replacement bool,
+) (*SignedTransaction, bool, error) {
+	b.nonceLock.Lock()
+	defer b.nonceLock.Unlock()
+
+	hash := sigHash(tx, chainID)
+
+	existing, err := b.retrieveSignedTx(ctx, req, keyPair.Address, hash)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		b.Logger().Info("Returning previously signed transaction", "address", keyPair.Address, "tx_hash", existing.TxHash)
+		return &existing.SignedTransaction, true, nil
+	}
+
+	if err = b.checkNonce(ctx, req, keyPair.Address, tx, chainID, replacement); err != nil {
+		return nil, false, err
+	}
+
+	if err = b.countSignature(ctx, req, keyManager, keyPair); err != nil {
+		return nil, false, err
+	}
+
+	signed, err := b.signTransaction(keyManager, keyPair, tx, chainID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	record := &SignedTxRecord{
+		SignedTransaction: *signed,
+		SigHash:           hash,
+		Nonce:             tx.Nonce(),
+		ChainID:           chainID.String(),
+		SignedAt:          time.Now().UTC(),
+	}
+	entry, _ := logical.StorageEntryJSON(historyPath(keyPair.Address, hash), record)
+	if err = req.Storage.Put(ctx, entry); err != nil {
+		b.Logger().Error("Failed to save the signed transaction to storage", "address", keyPair.Address, "error", err)
+		return nil, false, err
+	}
+
+	if err = b.saveNonceRecord(ctx, req, keyPair.Address, tx, chainID, signed, hash); err != nil {
+		return nil, false, err
+	}
+
+	return signed, false, nil
+}
+
+// replayIdempotent returns the stored response of an earlier request made with the same
+// idempotency key, or nil when the key was not used yet. Reusing a key for a different request
+// is an error.
+func (b *Backend) replayIdempotent(
+	ctx context.Context,
+	req *logical.Request,
+	serviceName string,
+	key string,
+	fingerprint string,
+) (*logical.Response, error) {
+	if !idempotencyKeyRegex.MatchString(key) {
+		return nil, fmt.Errorf("invalid idempotencyKey, must be 1 to 128 letters, digits, '_', '-' or '.'")
+	}
+
+	path := idempotencyPath(serviceName, key)
+	entry, err := req.Storage.Get(ctx, path)
+	if err != nil {
+		b.Logger().Error("Failed to retrieve the idempotency record", "path", path, "error", err)
+		return nil, err
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	var record IdempotencyRecord
+	if err = entry.DecodeJSON(&record); err != nil {
+		b.Logger().Error("Failed to decode idempotency record", "path", path, "error", err)
+		return nil, err
+	}
+
+	if record.Fingerprint != fingerprint {
+		return nil, fmt.Errorf("idempotencyKey %s was already used for a different request", key)
+	}
+
+	data := make(map[string]interface{}, len(record.Response)+1)
+	for k, v := range record.Response {
+		data[k] = v
+	}
+	data["replayed"] = true
+	return &logical.Response{Data: data}, nil
+}
+
+func (b *Backend) saveIdempotent(
+	ctx context.Context,
+	req *logical.Request,
+	serviceName string,
+	key string,
+	fingerprint string,
+	resp *logical.Response,
+) error {
+	record := &IdempotencyRecord{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Response:    resp.Data,
+		CreatedAt:   time.Now().UTC(),
+	}
+	entry, _ := logical.StorageEntryJSON(idempotencyPath(serviceName, key), record)
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		b.Logger().Error("Failed to save the idempotency record to storage", "service_name", serviceName, "error", err)
+		return err
+	}
+	return nil
+}
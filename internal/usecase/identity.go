@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// identityBound reports whether the key-manager restricts signing to Vault identities.
+func (k *KeyManager) identityBound() bool {
+	return len(k.BoundEntities) > 0 || len(k.BoundGroups) > 0 || len(k.BoundMetadata) > 0
+}
+
+// checkIdentity rejects requests that sign with, derive or export the keys of the key-manager from
+// entities it is not bound to. The entity
+// is allowed when it is one of the bound entities, a member of one of the bound groups, or carries
+// every bound metadata value on the entity or on one of its aliases. Identities that cannot be
+// looked up are rejected. Rejections are coded 403 so that the status survives the plugin gRPC
+// boundary.
+func (b *Backend) checkIdentity(keyManager *KeyManager, entityID string) error {
+	if !keyManager.identityBound() {
+		return nil
+	}
+
+	denied := logical.CodedError(http.StatusForbidden, fmt.Sprintf("%s: entity %q is not bound to keyManager %s",
+		logical.ErrPermissionDenied, entityID, keyManager.ServiceName))
+	if entityID == "" || b.System() == nil {
+		return denied
+	}
+
+	entity, err := b.System().EntityInfo(entityID)
+	if err != nil {
+		b.Logger().Error("Failed to look up the requesting entity", "entity_id", entityID, "error", err)
+		return denied
+	}
+
+	for _, bound := range keyManager.BoundEntities {
+		if bound == entityID || (entity != nil && bound == entity.Name) {
+			return nil
+		}
+	}
+
+	if len(keyManager.BoundGroups) > 0 {
+		groups, err := b.System().GroupsForEntity(entityID)
+		if err != nil {
+			b.Logger().Error("Failed to look up the groups of the requesting entity", "entity_id", entityID, "error", err)
+			return denied
+		}
+		for _, group := range groups {
+			for _, bound := range keyManager.BoundGroups {
+				if bound == group.ID || bound == group.Name {
+					return nil
+				}
+			}
+		}
+	}
+
+	if len(keyManager.BoundMetadata) > 0 && entity != nil {
+		if matchMetadata(keyManager.BoundMetadata, entity.Metadata) {
+			return nil
+		}
+		for _, alias := range entity.Aliases {
+			if matchMetadata(keyManager.BoundMetadata, alias.Metadata) {
+				return nil
+			}
+		}
+	}
+
+	b.Logger().Warn("Request rejected by identity binding", "service_name", keyManager.ServiceName,
+		"entity_id", entityID)
+	return denied
+}
+
+func matchMetadata(bound, metadata map[string]string) bool {
+	for k, v := range bound {
+		if value, ok := metadata[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
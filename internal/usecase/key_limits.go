@@ -0,0 +1,101 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const keyStatusExpired = "expired"
+
+// keyOptions bound the lifetime and the number of signatures of new key pairs, and label them.
+type keyOptions struct {
+	notAfter      *time.Time
+	maxSignatures int
+	labels        map[string]string
+}
+
+func newKeyOptions(ttl time.Duration, maxSignatures int, labels map[string]string) (*keyOptions, error) {
+	if ttl < 0 {
+		return nil, fmt.Errorf("ttl cannot be negative")
+	}
+	if maxSignatures < 0 {
+		return nil, fmt.Errorf("maxSignatures cannot be negative")
+	}
+
+	options := &keyOptions{maxSignatures: maxSignatures, labels: labels}
+	if ttl > 0 {
+		notAfter := time.Now().UTC().Add(ttl)
+		options.notAfter = &notAfter
+	}
+	return options, nil
+}
+
+func (l *keyOptions) apply(keyPair *KeyPair) {
+	keyPair.NotAfter = l.notAfter
+	keyPair.MaxSignatures = l.maxSignatures
+	if len(l.labels) > 0 {
+		keyPair.Labels = l.labels
+	}
+}
+
+// expired reports whether the key pair is past its not-after time or used up its signatures.
+func (k *KeyPair) expired(now time.Time) bool {
+	if k.NotAfter != nil && !now.Before(*k.NotAfter) {
+		return true
+	}
+	return k.MaxSignatures > 0 && k.Signatures >= k.MaxSignatures
+}
+
+func (k *KeyPair) checkExpiry(now time.Time) error {
+	if k.NotAfter != nil && !now.Before(*k.NotAfter) {
+		return fmt.Errorf("address %s expired at %s", k.Address, k.NotAfter.Format(time.RFC3339))
+	}
+	if k.MaxSignatures > 0 && k.Signatures >= k.MaxSignatures {
+		return fmt.Errorf("address %s used all of its %d signatures", k.Address, k.MaxSignatures)
+	}
+	return nil
+}
+
+// countSignature records a signature of a key pair with a maximum number of signatures, failing
+// once the maximum is reached. The count is taken before signing, so a signature that fails
+// afterwards still counts.
+func (b *Backend) countSignature(
+	ctx context.Context,
+	req *logical.Request,
+	keyManager *KeyManager,
+	keyPair *KeyPair,
+) error {
+	if keyPair.MaxSignatures == 0 {
+		return nil
+	}
+
+	b.keyManagerLock.Lock()
+	defer b.keyManagerLock.Unlock()
+
+	// count against the stored key-manager, other requests may have signed since it was read
+	stored, err := b.retrieveKeyManager(ctx, req, keyManager.ServiceName)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		return fmt.Errorf("signing keyManager %s does not exist", keyManager.ServiceName)
+	}
+	storedKeyPair := stored.keyPair(keyPair.Address)
+	if storedKeyPair == nil {
+		return fmt.Errorf("no private key for the input address")
+	}
+
+	if err = storedKeyPair.checkExpiry(time.Now()); err != nil {
+		return err
+	}
+
+	storedKeyPair.Signatures++
+	if err = b.saveKeyManager(ctx, req, stored); err != nil {
+		return err
+	}
+	keyPair.Signatures = storedKeyPair.Signatures
+	return nil
+}
@@ -2,30 +2,118 @@ package usecase
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
 type KeyPair struct {
-	PrivateKey string `json:"private_key"`
-	PublicKey  string `json:"public_key"`
-	Address    string `json:"address"`
+	PrivateKey     string `json:"private_key"`
+	PublicKey      string `json:"public_key"`
+	Address        string `json:"address"`
+	DerivationPath string `json:"derivation_path,omitempty"`
+	DerivationID   string `json:"derivation_id,omitempty"`
+
+	// RetiredAt is set once the key pair was rotated. Retired keys still verify, but only sign when
+	// the key-manager allows retired signing.
+	RetiredAt   *time.Time `json:"retired_at,omitempty"`
+	Successor   string     `json:"successor,omitempty"`
+	Predecessor string     `json:"predecessor,omitempty"`
+
+	// NotAfter and MaxSignatures expire the key pair, after which it no longer signs.
+	NotAfter      *time.Time `json:"not_after,omitempty"`
+	MaxSignatures int        `json:"max_signatures,omitempty"`
+	Signatures    int        `json:"signatures,omitempty"`
+
+	// Session marks a key pair that lives as long as a Vault lease.
+	Session bool `json:"session,omitempty"`
+
+	// Pooled marks a pre-generated key pair that was not claimed yet.
+	Pooled bool `json:"pooled,omitempty"`
+
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 type KeyManager struct {
-	ServiceName string     `json:"service_name"`
-	KeyPairs    []*KeyPair `json:"key_pairs"`
+	ServiceName      string     `json:"service_name"`
+	KeyPairs         []*KeyPair `json:"key_pairs"`
+	Seed             string     `json:"seed,omitempty"`
+	NextIndex        uint32     `json:"next_index,omitempty"`
+	AllowedChainIDs  []string   `json:"allowed_chain_ids"`
+	AllowUnprotected bool       `json:"allow_unprotected"`
+	Purposes         []string   `json:"purposes"`
+	Policies         []string   `json:"policies"`
+
+	Approvers         []string      `json:"approvers"`
+	RequiredApprovals int           `json:"required_approvals"`
+	ApprovalTTL       time.Duration `json:"approval_ttl"`
+
+	SigningDelay time.Duration `json:"signing_delay"`
+
+	Exportable bool `json:"exportable"`
+
+	AllowRetiredSigning bool `json:"allow_retired_signing"`
+
+	PoolSize int `json:"pool_size,omitempty"`
+
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// BoundEntities, BoundGroups and BoundMetadata restrict signing to these Vault identities.
+	BoundEntities []string          `json:"bound_entities,omitempty"`
+	BoundGroups   []string          `json:"bound_groups,omitempty"`
+	BoundMetadata map[string]string `json:"bound_metadata,omitempty"`
+
+	RateLimit        *RateLimit `json:"rate_limit,omitempty"`
+	AddressRateLimit *RateLimit `json:"address_rate_limit,omitempty"`
 }
 
+// maxKeyBatch bounds the keys created by a single request or pool refill
+const maxKeyBatch = 1000
+
+const (
+	purposeHash        = "hash"
+	purposeTransaction = "transaction"
+	purposeTypedData   = "typed_data"
+)
+
+const (
+	operationSign          = "sign"
+	operationSignTx        = "signTx"
+	operationSignTypedData = "signTypedData"
+)
+
 func paths(b *Backend) []*framework.Path {
-	return []*framework.Path{
-		pathCreateAndList(b),
-		pathReadAndDelete(b),
-		pathSign(b),
-		pathSignTx(b),
-	}
+	return framework.PathAppend(
+		[]*framework.Path{
+			pathCreateAndList(b),
+			pathReadAndDelete(b),
+			pathSign(b),
+			pathSignTx(b),
+			pathSignTypedData(b),
+			pathKeyManagerConfig(b),
+			pathDerive(b),
+			pathSession(b),
+			pathPool(b),
+			pathConfig(b),
+		},
+		pathAddresses(b),
+		pathPolicies(b),
+		pathApprovals(b),
+		pathTimeLocks(b),
+		pathAudit(b),
+		pathHistory(b),
+		pathKeystore(b),
+		pathWrappedImport(b),
+		pathBackup(b),
+		pathEscrow(b),
+		pathRotate(b),
+	)
 }
 
 func (b *Backend) retrieveKeyManager(
@@ -53,3 +141,159 @@ func (b *Backend) retrieveKeyManager(
 	}
 	return &policy, nil
 }
+
+func (b *Backend) saveKeyManager(
+	ctx context.Context,
+	req *logical.Request,
+	keyManager *KeyManager,
+) error {
+	path := fmt.Sprintf("key-managers/%s", keyManager.ServiceName)
+	entry, _ := logical.StorageEntryJSON(path, keyManager)
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		b.Logger().Error("Failed to save the keyManager to storage", "path", path, "error", err)
+		return err
+	}
+	return b.saveKeyManagerInfo(ctx, req.Storage, keyManager)
+}
+
+// keyPair returns the key pair of the key-manager that owns the address, or nil.
+func (k *KeyManager) keyPair(address string) *KeyPair {
+	if i := k.keyPairIndex(address); i >= 0 {
+		return k.KeyPairs[i]
+	}
+	return nil
+}
+
+func (k *KeyManager) keyPairIndex(address string) int {
+	for i, keyPair := range k.KeyPairs {
+		if strings.EqualFold(keyPair.Address, address) {
+			return i
+		}
+	}
+	return -1
+}
+
+// newKeyPair returns the standalone key pair of the private key.
+func newKeyPair(privateKey *ecdsa.PrivateKey) *KeyPair {
+	return &KeyPair{
+		PrivateKey: common.Bytes2Hex(crypto.FromECDSA(privateKey)),
+		PublicKey:  common.Bytes2Hex(crypto.FromECDSAPub(&privateKey.PublicKey)),
+		Address:    crypto.PubkeyToAddress(privateKey.PublicKey).Hex(),
+	}
+}
+
+// generateKeyPair returns a new key pair for the key-manager: the next BIP-44 key of an HD
+// key-manager, or a newly generated key otherwise.
+func (k *KeyManager) generateKeyPair() (*KeyPair, error) {
+	if k.Seed != "" {
+		keyPair, err := newHDKeyPair(common.FromHex(k.Seed), fmt.Sprintf(bip44PathFormat, k.NextIndex))
+		if err != nil {
+			return nil, err
+		}
+		k.NextIndex++
+		return keyPair, nil
+	}
+
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(privateKey)
+	return newKeyPair(privateKey), nil
+}
+
+// generateKeyPairs appends count new key pairs to the key-manager.
+func (k *KeyManager) generateKeyPairs(count int, options *keyOptions) ([]*KeyPair, error) {
+	keyPairs := make([]*KeyPair, 0, count)
+	for len(keyPairs) < count {
+		keyPair, err := k.generateKeyPair()
+		if err != nil {
+			return nil, err
+		}
+		options.apply(keyPair)
+		keyPairs = append(keyPairs, keyPair)
+	}
+	k.KeyPairs = append(k.KeyPairs, keyPairs...)
+	return keyPairs, nil
+}
+
+func addressesOf(keyPairs []*KeyPair) []string {
+	addresses := make([]string, len(keyPairs))
+	for i := range keyPairs {
+		addresses[i] = keyPairs[i].Address
+	}
+	return addresses
+}
+
+// checkPurpose rejects signing requests for purposes the key-manager is not restricted to.
+// A key-manager without purposes may be used for every kind of signing.
+func (k *KeyManager) checkPurpose(purpose string) error {
+	if len(k.Purposes) == 0 {
+		return nil
+	}
+	for _, p := range k.Purposes {
+		if p == purpose {
+			return nil
+		}
+	}
+	return fmt.Errorf("keyManager %s is not allowed to sign for purpose %q", k.ServiceName, purpose)
+}
+
+func parsePurposes(input []string) ([]string, error) {
+	for _, purpose := range input {
+		switch purpose {
+		case purposeHash, purposeTransaction, purposeTypedData:
+		default:
+			return nil, fmt.Errorf("invalid purpose %q, must be one of %q, %q or %q",
+				purpose, purposeHash, purposeTransaction, purposeTypedData)
+		}
+	}
+	return input, nil
+}
+
+// importKeyPair adds the private key to the key-manager, creating the key-manager when it does
+// not exist yet.
+func (b *Backend) importKeyPair(
+	ctx context.Context,
+	req *logical.Request,
+	serviceName string,
+	privateKey *ecdsa.PrivateKey,
+) (*logical.Response, error) {
+	b.keyManagerLock.Lock()
+	defer b.keyManagerLock.Unlock()
+
+	keyManager, err := b.retrieveKeyManager(ctx, req, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyManager == nil {
+		keyManager = &KeyManager{
+			ServiceName: serviceName,
+		}
+	}
+
+	if keyManager.Seed != "" {
+		return nil, fmt.Errorf("keys cannot be imported into HD keyManager %s", serviceName)
+	}
+
+	keyPair := newKeyPair(privateKey)
+	if keyManager.keyPair(keyPair.Address) != nil {
+		return nil, fmt.Errorf("address %s already belongs to keyManager %s", keyPair.Address, serviceName)
+	}
+
+	keyManager.KeyPairs = append(keyManager.KeyPairs, keyPair)
+	if err = b.saveKeyManager(ctx, req, keyManager); err != nil {
+		return nil, err
+	}
+
+	emitKeyManagerMetric(metricKeyManagerCreated, keyManager.ServiceName)
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"service_name": keyManager.ServiceName,
+			"address":      keyPair.Address,
+			"public_key":   keyPair.PublicKey,
+		},
+	}, nil
+}
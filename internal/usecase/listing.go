@@ -0,0 +1,161 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// keyManagerInfoPath holds the listing metadata of every key-manager, so that listing them does not
+// decode their key material.
+const keyManagerInfoPath = "key-manager-info/"
+
+// KeyManagerInfo is the listing metadata of a key-manager.
+type KeyManagerInfo struct {
+	KeyCount int               `json:"key_count"`
+	HD       bool              `json:"hd"`
+	Labels   map[string]string `json:"labels"`
+}
+
+func (k *KeyManager) listingInfo() *KeyManagerInfo {
+	return &KeyManagerInfo{
+		KeyCount: len(k.KeyPairs),
+		HD:       k.Seed != "",
+		Labels:   k.Labels,
+	}
+}
+
+func (i *KeyManagerInfo) response() map[string]interface{} {
+	return map[string]interface{}{
+		"key_count": i.KeyCount,
+		"hd":        i.HD,
+		"labels":    i.Labels,
+	}
+}
+
+func (b *Backend) saveKeyManagerInfo(ctx context.Context, storage logical.Storage, keyManager *KeyManager) error {
+	entry, _ := logical.StorageEntryJSON(keyManagerInfoPath+keyManager.ServiceName, keyManager.listingInfo())
+	if err := storage.Put(ctx, entry); err != nil {
+		b.Logger().Error("Failed to save the keyManager listing info to storage",
+			"service_name", keyManager.ServiceName, "error", err)
+		return err
+	}
+	return nil
+}
+
+// retrieveKeyManagerInfo returns the listing metadata of the key-manager. Key-managers saved before
+// the metadata was kept are decoded instead.
+func (b *Backend) retrieveKeyManagerInfo(
+	ctx context.Context,
+	req *logical.Request,
+	serviceName string,
+) (*KeyManagerInfo, error) {
+	entry, err := req.Storage.Get(ctx, keyManagerInfoPath+serviceName)
+	if err != nil {
+		b.Logger().Error("Failed to retrieve the keyManager listing info", "service_name", serviceName, "error", err)
+		return nil, err
+	}
+
+	if entry == nil {
+		keyManager, err := b.retrieveKeyManager(ctx, req, serviceName)
+		if err != nil || keyManager == nil {
+			return nil, err
+		}
+		return keyManager.listingInfo(), nil
+	}
+
+	var info KeyManagerInfo
+	if err = entry.DecodeJSON(&info); err != nil {
+		b.Logger().Error("Failed to decode keyManager listing info", "service_name", serviceName, "error", err)
+		return nil, err
+	}
+	return &info, nil
+}
+
+// withListFields adds the pagination and filter fields of the listings to the fields of a path.
+func withListFields(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	for name, field := range listFields() {
+		fields[name] = field
+	}
+	return fields
+}
+
+func listFields() map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"after": {
+			Type:        framework.TypeString,
+			Description: "(optional) Cursor to continue the listing after, the next_after of the previous page.",
+		},
+		"limit": {
+			Type:        framework.TypeInt,
+			Description: "(optional) Maximum number of entries to return. 0 returns every entry.",
+		},
+		"prefix": {
+			Type:        framework.TypeString,
+			Description: "(optional) Only return entries starting with the prefix.",
+		},
+		"label": {
+			Type:        framework.TypeKVPairs,
+			Description: "(optional) Only return entries carrying every one of the key=value labels.",
+		},
+	}
+}
+
+// listFilter selects a page of a listing.
+type listFilter struct {
+	after  string
+	limit  int
+	prefix string
+	labels map[string]string
+}
+
+func newListFilter(data *framework.FieldData) (*listFilter, error) {
+	filter := &listFilter{
+		after:  data.Get("after").(string),
+		limit:  data.Get("limit").(int),
+		prefix: data.Get("prefix").(string),
+		labels: data.Get("label").(map[string]string),
+	}
+	if filter.limit < 0 {
+		return nil, fmt.Errorf("limit cannot be negative")
+	}
+	return filter, nil
+}
+
+func (f *listFilter) matchPrefix(key string) bool {
+	return strings.HasPrefix(strings.ToLower(key), strings.ToLower(f.prefix))
+}
+
+func (f *listFilter) matchLabels(labels map[string]string) bool {
+	for k, v := range f.labels {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *listFilter) full(n int) bool {
+	return f.limit > 0 && n >= f.limit
+}
+
+// page returns the sorted names after the cursor that start with the prefix.
+func (f *listFilter) page(names []string) []string {
+	sort.Strings(names)
+	start := sort.SearchStrings(names, f.after)
+	if f.after != "" && start < len(names) && names[start] == f.after {
+		start++
+	}
+
+	page := make([]string, 0, len(names)-start)
+	for _, name := range names[start:] {
+		if f.matchPrefix(name) {
+			page = append(page, name)
+		}
+	}
+	return page
+}
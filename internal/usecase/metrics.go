@@ -0,0 +1,47 @@
+package usecase
+
+import (
+	"time"
+
+	"github.com/armon/go-metrics"
+)
+
+// Metrics are emitted through the go-metrics global sink Vault configures for plugins, so they
+// show up next to Vault's own telemetry, e.g. as vault_ethsigner_sign_requests in Prometheus.
+var (
+	metricSignRequests      = []string{"ethsigner", "sign", "requests"}
+	metricSignDuration      = []string{"ethsigner", "sign", "duration"}
+	metricPolicyRejections  = []string{"ethsigner", "policy", "rejections"}
+	metricKeyManagerCreated = []string{"ethsigner", "key_manager", "created"}
+	metricKeyManagerDeleted = []string{"ethsigner", "key_manager", "deleted"}
+)
+
+// emitSigningMetrics counts a signing request by its outcome and measures its latency. It is
+// deferred by the signing handlers once the request's audit record holds the decision.
+func emitSigningMetrics(record *AuditRecord, start time.Time) {
+	labels := []metrics.Label{
+		{Name: "operation", Value: record.Operation},
+		{Name: "service_name", Value: record.ServiceName},
+		{Name: "decision", Value: record.Decision},
+	}
+	if record.ChainID != "" {
+		labels = append(labels, metrics.Label{Name: "chain_id", Value: record.ChainID})
+	}
+
+	metrics.IncrCounterWithLabels(metricSignRequests, 1, labels)
+	metrics.MeasureSinceWithLabels(metricSignDuration, start, labels)
+}
+
+func emitPolicyRejection(operation, serviceName, policy string) {
+	metrics.IncrCounterWithLabels(metricPolicyRejections, 1, []metrics.Label{
+		{Name: "operation", Value: operation},
+		{Name: "service_name", Value: serviceName},
+		{Name: "policy", Value: policy},
+	})
+}
+
+func emitKeyManagerMetric(key []string, serviceName string) {
+	metrics.IncrCounterWithLabels(key, 1, []metrics.Label{
+		{Name: "service_name", Value: serviceName},
+	})
+}
@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// NonceRecord is the transaction currently signed for an (address, chainId, nonce). Only a
+// replacement with strictly higher fees may take its place.
+type NonceRecord struct {
+	SigHash   string    `json:"sig_hash"`
+	TxHash    string    `json:"tx_hash"`
+	GasFeeCap string    `json:"gas_fee_cap"`
+	GasTipCap string    `json:"gas_tip_cap"`
+	SignedAt  time.Time `json:"signed_at"`
+}
+
+func noncePath(address string, chainID *big.Int, nonce uint64) string {
+	return fmt.Sprintf("nonces/%s/%s/%d", strings.ToLower(address), chainID, nonce)
+}
+
+func (b *Backend) retrieveNonceRecord(
+	ctx context.Context,
+	req *logical.Request,
+	address string,
+	chainID *big.Int,
+	nonce uint64,
+) (*NonceRecord, error) {
+	path := noncePath(address, chainID, nonce)
+	entry, err := req.Storage.Get(ctx, path)
+	if err != nil {
+		b.Logger().Error("Failed to retrieve the nonce record", "path", path, "error", err)
+		return nil, err
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	var record NonceRecord
+	if err = entry.DecodeJSON(&record); err != nil {
+		b.Logger().Error("Failed to decode nonce record", "path", path, "error", err)
+		return nil, err
+	}
+	return &record, nil
+}
+
+// checkNonce rejects a transaction that reuses the nonce of a different signed transaction,
+// unless it is a replacement paying strictly higher fees.
+func (b *Backend) checkNonce(
+	ctx context.Context,
+	req *logical.Request,
+	address string,
+	tx *types.Transaction,
+	chainID *big.Int,
+	replacement bool,
+) error {
+	existing, err := b.retrieveNonceRecord(ctx, req, address, chainID, tx.Nonce())
+	if err != nil {
+		return err
+	}
+	if existing == nil || existing.SigHash == sigHash(tx, chainID) {
+		return nil
+	}
+
+	if !replacement {
+		return fmt.Errorf("nonce %d on chainId %s was already signed for transaction %s, set replacement to replace it",
+			tx.Nonce(), chainID, existing.TxHash)
+	}
+
+	// legacy transactions report their gasPrice as both caps
+	feeCap, _ := new(big.Int).SetString(existing.GasFeeCap, 10)
+	tipCap, _ := new(big.Int).SetString(existing.GasTipCap, 10)
+	if feeCap == nil || tipCap == nil || tx.GasFeeCap().Cmp(feeCap) <= 0 || tx.GasTipCap().Cmp(tipCap) <= 0 {
+		return fmt.Errorf("replacement for nonce %d on chainId %s must pay strictly higher fees than transaction %s",
+			tx.Nonce(), chainID, existing.TxHash)
+	}
+	return nil
+}
+
+func (b *Backend) saveNonceRecord(
+	ctx context.Context,
+	req *logical.Request,
+	address string,
+	tx *types.Transaction,
+	chainID *big.Int,
+	signed *SignedTransaction,
+	hash string,
+) error {
+	record := &NonceRecord{
+		SigHash:   hash,
+		TxHash:    signed.TxHash,
+		GasFeeCap: tx.GasFeeCap().String(),
+		GasTipCap: tx.GasTipCap().String(),
+		SignedAt:  time.Now().UTC(),
+	}
+	entry, _ := logical.StorageEntryJSON(noncePath(address, chainID, tx.Nonce()), record)
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		b.Logger().Error("Failed to save the nonce record to storage", "address", address, "error", err)
+		return err
+	}
+	return nil
+}
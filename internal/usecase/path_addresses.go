@@ -0,0 +1,25 @@
+package usecase
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+)
+
+// addressRegex matches a hex encoded address path segment into the address field.
+const addressRegex = `(?P<address>0x[0-9a-fA-F]{40})`
+
+// pathAddresses serves the signing paths per address as well, as in
+// key-managers/<name>/addresses/<address>/txn/sign, so that Vault ACL policies can grant signing
+// with a single address. The address in the path takes precedence over the one in the body.
+func pathAddresses(b *Backend) []*framework.Path {
+	return []*framework.Path{
+		perAddressPath(pathSign(b), "/sign"),
+		perAddressPath(pathSignTx(b), "/txn/sign"),
+		perAddressPath(pathSignTypedData(b), "/typed-data/sign"),
+	}
+}
+
+func perAddressPath(path *framework.Path, suffix string) *framework.Path {
+	perAddress := *path
+	perAddress.Pattern = "key-managers/" + framework.GenericNameRegex("name") + "/addresses/" + addressRegex + suffix
+	return &perAddress
+}
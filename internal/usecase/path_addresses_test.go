@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackend_signPerAddress(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const (
+		testSvc = "test-service"
+		address = "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704"
+	)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": testSvc,
+		"privateKey":  "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"serviceName": testSvc,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	otherAddress := resp.Data["address"].(string)
+
+	hash := crypto.Keccak256Hash([]byte("data that should be signed"))
+	sign := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, path)
+		req.Storage = storage
+		req.Data = data
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	// the address in the path wins over the one in the body
+	resp, err = sign("key-managers/"+testSvc+"/addresses/"+address+"/sign", map[string]interface{}{
+		"hash":    hash.Hex(),
+		"address": otherAddress,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	publicKey, err := crypto.SigToPub(hash.Bytes(), common.FromHex(resp.Data["signature"].(string)))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, address, crypto.PubkeyToAddress(*publicKey).Hex())
+
+	// addresses in the path are matched regardless of their checksum case
+	resp, err = sign("key-managers/"+testSvc+"/addresses/"+strings.ToLower(address)+"/txn/sign", map[string]interface{}{
+		"data":     "0x",
+		"to":       "0xf809410b0d6f047c603deb311979cd413e025a84",
+		"gas":      21000,
+		"nonce":    "0x0",
+		"gasPrice": 1,
+		"chainId":  "1",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.NotEmpty(t, resp.Data["signedTx"])
+
+	_, err = sign("key-managers/"+testSvc+"/addresses/0x0000000000000000000000000000000000000001/sign", map[string]interface{}{
+		"hash": hash.Hex(),
+	})
+	assert.EqualError(t, err, "no private key for the input address")
+
+	// signing is an update, there is nothing to create
+	req = logical.TestRequest(t, logical.CreateOperation, "key-managers/"+testSvc+"/addresses/"+address+"/sign")
+	req.Storage = storage
+	_, err = b.HandleRequest(context.Background(), req)
+	assert.ErrorIs(t, err, logical.ErrUnsupportedOperation)
+}
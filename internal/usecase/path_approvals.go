@@ -0,0 +1,321 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	pendingStatusPending  = "pending"
+	pendingStatusApproved = "approved"
+	pendingStatusExpired  = "expired"
+
+	defaultApprovalTTL = 24 * time.Hour
+
+	// expired approval requests stay readable for this long before they are pruned
+	expiredApprovalRetention = 24 * time.Hour
+)
+
+// PendingTransaction is a signTx request held back until enough approvers have approved it.
+type PendingTransaction struct {
+	QueuedTransaction
+	ExpiresAt         time.Time   `json:"expires_at"`
+	Approvers         []string    `json:"approvers"`
+	RequiredApprovals int         `json:"required_approvals"`
+	Approvals         []*Approval `json:"approvals"`
+	Status            string      `json:"status"`
+}
+
+// Approval records a Vault entity approving a pending transaction.
+type Approval struct {
+	EntityID   string    `json:"entity_id"`
+	ApprovedAt time.Time `json:"approved_at"`
+}
+
+func (p *PendingTransaction) currentStatus(now time.Time) string {
+	if p.Status == pendingStatusPending && now.After(p.ExpiresAt) {
+		return pendingStatusExpired
+	}
+	return p.Status
+}
+
+func pathApprovals(b *Backend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "approvals/?",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.listApprovals,
+				},
+			},
+			HelpSynopsis: "List the transactions waiting for or released by approvals.",
+		},
+		{
+			Pattern: "approvals/" + framework.GenericNameRegex("id"),
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.readApproval,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.approve,
+				},
+			},
+			HelpSynopsis: "Read or approve a transaction that requires approvals.",
+			HelpDescription: `
+
+    GET - return the pending transaction, its approvals and, once approved, the signed transaction
+    POST - approve the pending transaction as the calling Vault entity
+
+    `,
+			Fields: map[string]*framework.FieldSchema{
+				"id": {Type: framework.TypeString},
+			},
+		},
+	}
+}
+
+func (b *Backend) queueForApproval(
+	ctx context.Context,
+	req *logical.Request,
+	keyManager *KeyManager,
+	fields *RequestFieldsTransaction,
+) (*logical.Response, error) {
+	if keyManager.RequiredApprovals == 0 || len(keyManager.Approvers) < keyManager.RequiredApprovals {
+		return nil, fmt.Errorf("transaction requires approval but keyManager %s has no approvers configured",
+			keyManager.ServiceName)
+	}
+
+	queued, err := newQueuedTransaction(req, keyManager, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := keyManager.ApprovalTTL
+	if ttl == 0 {
+		ttl = defaultApprovalTTL
+	}
+
+	pending := &PendingTransaction{
+		QueuedTransaction: *queued,
+		ExpiresAt:         queued.CreatedAt.Add(ttl),
+		Approvers:         keyManager.Approvers,
+		RequiredApprovals: keyManager.RequiredApprovals,
+		Status:            pendingStatusPending,
+	}
+
+	if err = b.savePendingTransaction(ctx, req, pending); err != nil {
+		return nil, err
+	}
+
+	b.Logger().Info("Transaction queued for approval", "service_name", keyManager.ServiceName,
+		"approval_id", pending.ID, "required_approvals", pending.RequiredApprovals)
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"status":            pendingStatusPending,
+			"approvalId":        pending.ID,
+			"requiredApprovals": pending.RequiredApprovals,
+			"expiresAt":         pending.ExpiresAt,
+		},
+	}, nil
+}
+
+func (b *Backend) retrievePendingTransaction(
+	ctx context.Context,
+	req *logical.Request,
+	id string,
+) (*PendingTransaction, error) {
+	path := fmt.Sprintf("approvals/%s", id)
+	entry, err := req.Storage.Get(ctx, path)
+	if err != nil {
+		b.Logger().Error("Failed to retrieve the pending transaction", "path", path, "error", err)
+		return nil, err
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	var pending PendingTransaction
+	if err = entry.DecodeJSON(&pending); err != nil {
+		b.Logger().Error("Failed to decode pending transaction", "path", path, "error", err)
+		return nil, err
+	}
+	return &pending, nil
+}
+
+func (b *Backend) savePendingTransaction(
+	ctx context.Context,
+	req *logical.Request,
+	pending *PendingTransaction,
+) error {
+	entry, _ := logical.StorageEntryJSON(fmt.Sprintf("approvals/%s", pending.ID), pending)
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		b.Logger().Error("Failed to save the pending transaction to storage", "id", pending.ID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// pruneExpiredApprovals deletes the approval requests that expired more than
+// expiredApprovalRetention ago.
+func (b *Backend) pruneExpiredApprovals(ctx context.Context, storage logical.Storage, now time.Time) error {
+	b.queueLock.Lock()
+	defer b.queueLock.Unlock()
+
+	ids, err := storage.List(ctx, "approvals/")
+	if err != nil {
+		b.Logger().Error("Failed to retrieve the list of pending transactions", "error", err)
+		return err
+	}
+
+	req := &logical.Request{Storage: storage}
+	for _, id := range ids {
+		pending, err := b.retrievePendingTransaction(ctx, req, id)
+		if err != nil {
+			return err
+		}
+		if pending == nil || pending.currentStatus(now) != pendingStatusExpired ||
+			now.Sub(pending.ExpiresAt) < expiredApprovalRetention {
+			continue
+		}
+		if err = storage.Delete(ctx, fmt.Sprintf("approvals/%s", id)); err != nil {
+			b.Logger().Error("Failed to delete the expired pending transaction", "id", id, "error", err)
+			return err
+		}
+		b.Logger().Info("Expired approval request pruned", "service_name", pending.ServiceName, "approval_id", id)
+	}
+	return nil
+}
+
+func (b *Backend) listApprovals(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	ids, err := req.Storage.List(ctx, "approvals/")
+	if err != nil {
+		b.Logger().Error("Failed to retrieve the list of pending transactions", "error", err)
+		return nil, err
+	}
+
+	now := time.Now()
+	keyInfo := make(map[string]interface{}, len(ids))
+	for _, id := range ids {
+		pending, err := b.retrievePendingTransaction(ctx, req, id)
+		if err != nil {
+			return nil, err
+		}
+		if pending == nil {
+			continue
+		}
+		keyInfo[id] = map[string]interface{}{
+			"service_name": pending.ServiceName,
+			"address":      pending.Address,
+			"status":       pending.currentStatus(now),
+			"approvals":    len(pending.Approvals),
+			"expires_at":   pending.ExpiresAt,
+		}
+	}
+
+	return logical.ListResponseWithInfo(ids, keyInfo), nil
+}
+
+func (b *Backend) readApproval(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	id, ok := data.Get("id").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	pending, err := b.retrievePendingTransaction(ctx, req, id)
+	if err != nil {
+		return nil, err
+	}
+	if pending == nil {
+		return nil, fmt.Errorf("approval request does not exist")
+	}
+
+	return pendingTransactionResponse(pending), nil
+}
+
+func (b *Backend) approve(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	id, ok := data.Get("id").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	if req.EntityID == "" {
+		return nil, errors.New("approvals must be made by a Vault entity")
+	}
+
+	b.queueLock.Lock()
+	defer b.queueLock.Unlock()
+
+	pending, err := b.retrievePendingTransaction(ctx, req, id)
+	if err != nil {
+		return nil, err
+	}
+	if pending == nil {
+		return nil, fmt.Errorf("approval request does not exist")
+	}
+
+	if status := pending.currentStatus(time.Now()); status != pendingStatusPending {
+		return nil, fmt.Errorf("approval request %s is %s", id, status)
+	}
+
+	if req.EntityID == pending.RequestedBy {
+		return nil, errors.New("requesters cannot approve their own transactions")
+	}
+
+	if !containsString(pending.Approvers, req.EntityID) {
+		return nil, fmt.Errorf("entity %s is not an approver of keyManager %s", req.EntityID, pending.ServiceName)
+	}
+
+	for _, approval := range pending.Approvals {
+		if approval.EntityID == req.EntityID {
+			return nil, fmt.Errorf("entity %s has already approved request %s", req.EntityID, id)
+		}
+	}
+
+	pending.Approvals = append(pending.Approvals, &Approval{
+		EntityID:   req.EntityID,
+		ApprovedAt: time.Now().UTC(),
+	})
+
+	if len(pending.Approvals) >= pending.RequiredApprovals {
+		if pending.Result, err = b.signQueuedTransaction(ctx, req, &pending.QueuedTransaction,
+			fmt.Sprintf("released by approval %s", id)); err != nil {
+			return nil, err
+		}
+		pending.Status = pendingStatusApproved
+		b.Logger().Info("Approved transaction signed", "service_name", pending.ServiceName, "approval_id", id)
+	}
+
+	if err = b.savePendingTransaction(ctx, req, pending); err != nil {
+		return nil, err
+	}
+
+	return pendingTransactionResponse(pending), nil
+}
+
+func pendingTransactionResponse(pending *PendingTransaction) *logical.Response {
+	resp := pending.response()
+	resp.Data["status"] = pending.currentStatus(time.Now())
+	resp.Data["expires_at"] = pending.ExpiresAt
+	resp.Data["required_approvals"] = pending.RequiredApprovals
+	resp.Data["approvals"] = pending.Approvals
+	return resp
+}
@@ -0,0 +1,258 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackend_approvals(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const (
+		treasurySvc = "treasury-service"
+		address     = "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704"
+		privateKey  = "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1"
+	)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": treasurySvc,
+		"privateKey":  privateKey,
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "policies/thresholds")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{
+				"name":       "large-value",
+				"expression": `tx.value >= 1e18`,
+				"effect":     "require_approval",
+			},
+			map[string]interface{}{
+				"name":       "default",
+				"expression": `true`,
+				"effect":     "allow",
+			},
+		},
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+treasurySvc+"/config")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"policies":          "thresholds",
+		"approvers":         "alice,bob,carol",
+		"requiredApprovals": 2,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	signTx := func(nonce string, value string) *logical.Response {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+treasurySvc+"/txn/sign")
+		req.Storage = storage
+		req.EntityID = "requester"
+		req.Data = map[string]interface{}{
+			"address":  address,
+			"data":     "0x",
+			"to":       "0xf809410b0d6f047c603deb311979cd413e025a84",
+			"value":    value,
+			"gas":      21000,
+			"nonce":    nonce,
+			"gasPrice": 1,
+			"chainId":  "1",
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return resp
+	}
+
+	// small transfers are signed right away
+	resp := signTx("0x1", "1000")
+	assert.NotEmpty(t, resp.Data["signedTx"])
+
+	resp = signTx("0x2", "5000000000000000000")
+	assert.Equal(t, "pending", resp.Data["status"])
+	assert.Nil(t, resp.Data["signedTx"])
+	id := resp.Data["approvalId"].(string)
+
+	approve := func(entityID string) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "approvals/"+id)
+		req.Storage = storage
+		req.EntityID = entityID
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	_, err = approve("requester")
+	assert.EqualError(t, err, "requesters cannot approve their own transactions")
+
+	_, err = approve("mallory")
+	assert.EqualError(t, err, "entity mallory is not an approver of keyManager "+treasurySvc)
+
+	resp, err = approve("alice")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, "pending", resp.Data["status"])
+	assert.Nil(t, resp.Data["signedTx"])
+
+	_, err = approve("alice")
+	assert.EqualError(t, err, "entity alice has already approved request "+id)
+
+	// the last approval checks the transaction against the current settings of the key-manager
+	configure := func(data map[string]interface{}) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+treasurySvc+"/config")
+		req.Storage = storage
+		req.Data = data
+		if _, err := b.HandleRequest(context.Background(), req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	configure(map[string]interface{}{"allowedChainIds": "137"})
+	_, err = approve("carol")
+	assert.EqualError(t, err, "chainId 1 is not allowed for keyManager "+treasurySvc)
+	configure(map[string]interface{}{"allowedChainIds": "1,137"})
+
+	resp, err = approve("carol")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, "approved", resp.Data["status"])
+	assert.Len(t, resp.Data["approvals"], 2)
+
+	signedTx, err := hexutil.Decode(resp.Data["signedTx"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := new(types.Transaction)
+	if err = tx.UnmarshalBinary(signedTx); err != nil {
+		t.Fatal(err)
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, address, sender.Hex())
+	assert.Equal(t, "5000000000000000000", tx.Value().String())
+
+	_, err = approve("bob")
+	assert.EqualError(t, err, "approval request "+id+" is approved")
+
+	req = logical.TestRequest(t, logical.ListOperation, "approvals")
+	req.Storage = storage
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, []string{id}, resp.Data["keys"])
+	assert.Equal(t, "approved", resp.Data["key_info"].(map[string]interface{})[id].(map[string]interface{})["status"])
+}
+
+func TestBackend_approvalsFailure1(t *testing.T) {
+	b, _ := newTestBackend(t)
+	req := logical.TestRequest(t, logical.UpdateOperation, "approvals/unknown")
+	req.EntityID = "alice"
+	_, err := b.HandleRequest(context.Background(), req)
+
+	assert.EqualError(t, err, "approval request does not exist")
+}
+
+func TestBackend_approvalsPruneExpired(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const treasurySvc = "treasury-service"
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": treasurySvc,
+		"privateKey":  "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "policies/approvals")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{"name": "everything", "expression": `true`, "effect": "require_approval"},
+		},
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+treasurySvc+"/config")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"policies":          "approvals",
+		"approvers":         "alice,bob",
+		"requiredApprovals": 1,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+treasurySvc+"/txn/sign")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"address":  "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704",
+		"data":     "0x",
+		"to":       "0xf809410b0d6f047c603deb311979cd413e025a84",
+		"value":    "1000",
+		"gas":      21000,
+		"nonce":    "0x1",
+		"gasPrice": 1,
+		"chainId":  "1",
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	id := resp.Data["approvalId"].(string)
+
+	list := func() interface{} {
+		req := logical.TestRequest(t, logical.ListOperation, "approvals")
+		req.Storage = storage
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return resp.Data["keys"]
+	}
+
+	// expired requests stay readable for the retention period
+	expired := time.Now().Add(defaultApprovalTTL + time.Minute)
+	if err = b.(*Backend).pruneExpiredApprovals(context.Background(), storage, expired); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, []string{id}, list())
+
+	if err = b.(*Backend).pruneExpiredApprovals(context.Background(), storage,
+		expired.Add(expiredApprovalRetention)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Nil(t, list())
+}
@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathAudit(b *Backend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "audit/?",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.listAuditRecords,
+				},
+			},
+			HelpSynopsis: "List the signing audit records, optionally filtered by address, key-manager and time range.",
+			HelpDescription: `
+
+    LIST - list the audit records oldest first. Pass limit to page through them, and the next_after
+    of a page as after to read the next one. Audit records carry no labels.
+
+    `,
+			Fields: withListFields(map[string]*framework.FieldSchema{
+				"address": {
+					Type:        framework.TypeString,
+					Description: "(optional) Only return records of this signing address.",
+				},
+				"serviceName": {
+					Type:        framework.TypeString,
+					Description: "(optional) Only return records of this key-manager.",
+				},
+				"since": {
+					Type:        framework.TypeString,
+					Description: "(optional) RFC3339 time of the oldest record to return.",
+				},
+				"until": {
+					Type:        framework.TypeString,
+					Description: "(optional) RFC3339 time of the newest record to return.",
+				},
+			}),
+		},
+		{
+			Pattern: "audit/" + framework.GenericNameRegex("id"),
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.readAuditRecord,
+				},
+			},
+			HelpSynopsis: "Read a signing audit record by its id.",
+			Fields: map[string]*framework.FieldSchema{
+				"id": {Type: framework.TypeString},
+			},
+		},
+	}
+}
+
+func (b *Backend) retrieveAuditRecord(ctx context.Context, req *logical.Request, id string) (*AuditRecord, error) {
+	t, err := auditTime(id)
+	if err != nil {
+		return nil, err
+	}
+
+	path := auditPath(id, t)
+	entry, err := req.Storage.Get(ctx, path)
+	if err != nil {
+		b.Logger().Error("Failed to retrieve the audit record", "path", path, "error", err)
+		return nil, err
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	var record AuditRecord
+	if err = entry.DecodeJSON(&record); err != nil {
+		b.Logger().Error("Failed to decode audit record", "path", path, "error", err)
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (b *Backend) listAuditRecords(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	filter, err := newListFilter(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(filter.labels) > 0 {
+		return nil, fmt.Errorf("audit records cannot be filtered by label")
+	}
+
+	address := data.Get("address").(string)
+	serviceName := data.Get("serviceName").(string)
+
+	since, err := parseOptionalTime(data.Get("since").(string))
+	if err != nil {
+		return nil, fmt.Errorf("invalid since: %w", err)
+	}
+	until, err := parseOptionalTime(data.Get("until").(string))
+	if err != nil {
+		return nil, fmt.Errorf("invalid until: %w", err)
+	}
+
+	// the cursor is a record id, so the days before its own are skipped without being listed
+	var afterDay string
+	if filter.after != "" {
+		after, err := auditTime(filter.after)
+		if err != nil {
+			return nil, fmt.Errorf("invalid after: %w", err)
+		}
+		afterDay = after.UTC().Format(auditDayLayout)
+	}
+
+	days, err := req.Storage.List(ctx, "audit/")
+	if err != nil {
+		b.Logger().Error("Failed to retrieve the list of audit records", "error", err)
+		return nil, err
+	}
+	sort.Strings(days)
+
+	var ids []string
+	keyInfo := make(map[string]interface{})
+	var last string
+	for _, day := range days {
+		day = strings.TrimSuffix(day, "/")
+		if day < afterDay {
+			continue
+		}
+		if !since.IsZero() && day < since.UTC().Format(auditDayLayout) {
+			continue
+		}
+		if !until.IsZero() && day > until.UTC().Format(auditDayLayout) {
+			continue
+		}
+
+		dayIDs, err := req.Storage.List(ctx, fmt.Sprintf("audit/%s/", day))
+		if err != nil {
+			b.Logger().Error("Failed to retrieve the list of audit records", "day", day, "error", err)
+			return nil, err
+		}
+
+		for _, id := range filter.page(dayIDs) {
+			if filter.full(len(ids)) {
+				resp := logical.ListResponseWithInfo(ids, keyInfo)
+				resp.Data["next_after"] = last
+				return resp, nil
+			}
+			last = id
+
+			record, err := b.retrieveAuditRecord(ctx, req, id)
+			if err != nil {
+				return nil, err
+			}
+			if record == nil ||
+				(address != "" && !strings.EqualFold(record.Address, address)) ||
+				(serviceName != "" && record.ServiceName != serviceName) ||
+				(!since.IsZero() && record.Time.Before(since)) ||
+				(!until.IsZero() && record.Time.After(until)) {
+				continue
+			}
+
+			ids = append(ids, id)
+			keyInfo[id] = map[string]interface{}{
+				"time":         record.Time,
+				"operation":    record.Operation,
+				"service_name": record.ServiceName,
+				"address":      record.Address,
+				"decision":     record.Decision,
+			}
+		}
+	}
+
+	return logical.ListResponseWithInfo(ids, keyInfo), nil
+}
+
+func (b *Backend) readAuditRecord(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	id, ok := data.Get("id").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	record, err := b.retrieveAuditRecord(ctx, req, id)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, fmt.Errorf("audit record does not exist")
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"id":           record.ID,
+			"time":         record.Time,
+			"operation":    record.Operation,
+			"entity_id":    record.EntityID,
+			"service_name": record.ServiceName,
+			"address":      record.Address,
+			"decision":     record.Decision,
+		},
+	}
+	optional := map[string]string{
+		"chain_id": record.ChainID,
+		"to":       record.To,
+		"value":    record.Value,
+		"selector": record.Selector,
+		"hash":     record.Hash,
+		"tx_hash":  record.TxHash,
+		"reason":   record.Reason,
+	}
+	for k, v := range optional {
+		if v != "" {
+			resp.Data[k] = v
+		}
+	}
+	if record.Nonce != nil {
+		resp.Data["nonce"] = *record.Nonce
+	}
+	return resp, nil
+}
+
+func parseOptionalTime(input string) (time.Time, error) {
+	if input == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, input)
+}
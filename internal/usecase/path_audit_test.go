@@ -0,0 +1,149 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackend_audit(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const (
+		keeperSvc  = "keeper-service"
+		address    = "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704"
+		privateKey = "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1"
+	)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": keeperSvc,
+		"privateKey":  privateKey,
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	start := time.Now().UTC()
+
+	signTx := func(chainID string) error {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+keeperSvc+"/txn/sign")
+		req.Storage = storage
+		req.EntityID = "payouts"
+		req.Data = map[string]interface{}{
+			"address":  address,
+			"data":     "0xa9059cbb",
+			"to":       "0xf809410b0d6f047c603deb311979cd413e025a84",
+			"value":    "1000",
+			"gas":      60000,
+			"nonce":    "0x7",
+			"gasPrice": 1,
+			"chainId":  chainID,
+		}
+		_, err := b.HandleRequest(context.Background(), req)
+		return err
+	}
+
+	assert.NoError(t, signTx("1"))
+	assert.Error(t, signTx("0"))
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+keeperSvc+"/sign")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"hash":    "0xaf41db230000000000000000000000000000000000000000000000000000000000000023",
+		"address": "0x0000000000000000000000000000000000000001",
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	assert.Error(t, err)
+
+	list := func(data map[string]interface{}) *logical.Response {
+		req := logical.TestRequest(t, logical.ListOperation, "audit")
+		req.Storage = storage
+		req.Data = data
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return resp
+	}
+
+	resp := list(map[string]interface{}{
+		"address": address,
+		"since":   start.Format(time.RFC3339),
+	})
+	ids := resp.Data["keys"].([]string)
+	assert.Len(t, ids, 2)
+
+	req = logical.TestRequest(t, logical.ReadOperation, "audit/"+ids[0])
+	req.Storage = storage
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, "signed", resp.Data["decision"])
+	assert.Equal(t, "signTx", resp.Data["operation"])
+	assert.Equal(t, "payouts", resp.Data["entity_id"])
+	assert.Equal(t, keeperSvc, resp.Data["service_name"])
+	assert.Equal(t, "1", resp.Data["chain_id"])
+	assert.Equal(t, uint64(7), resp.Data["nonce"])
+	assert.Equal(t, "0xf809410b0D6F047c603deB311979CD413E025a84", resp.Data["to"])
+	assert.Equal(t, "1000", resp.Data["value"])
+	assert.Equal(t, "0xa9059cbb", resp.Data["selector"])
+	assert.NotEmpty(t, resp.Data["tx_hash"])
+
+	req = logical.TestRequest(t, logical.ReadOperation, "audit/"+ids[1])
+	req.Storage = storage
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, "rejected", resp.Data["decision"])
+	assert.Contains(t, resp.Data["reason"], "unprotected (pre-EIP155) transactions are not permitted")
+
+	resp = list(map[string]interface{}{})
+	assert.Len(t, resp.Data["keys"], 3)
+
+	resp = list(map[string]interface{}{
+		"until": start.Add(-time.Hour).Format(time.RFC3339),
+	})
+	assert.Nil(t, resp.Data["keys"])
+
+	// paging through the records returns each of them once, oldest first
+	var paged []string
+	data := map[string]interface{}{"limit": 2}
+	for {
+		resp = list(data)
+		paged = append(paged, resp.Data["keys"].([]string)...)
+		after, ok := resp.Data["next_after"]
+		if !ok {
+			break
+		}
+		data = map[string]interface{}{"limit": 2, "after": after}
+	}
+	assert.Len(t, paged, 3)
+	assert.Equal(t, ids, paged[:2])
+}
+
+func TestBackend_listAuditRecordsFailure1(t *testing.T) {
+	b, _ := newTestBackend(t)
+	req := logical.TestRequest(t, logical.ListOperation, "audit")
+	req.Data = map[string]interface{}{
+		"after": "not-an-id",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+
+	assert.EqualError(t, err, `invalid after: invalid audit record id "not-an-id"`)
+}
+
+func TestBackend_readAuditRecordFailure1(t *testing.T) {
+	b, _ := newTestBackend(t)
+	req := logical.TestRequest(t, logical.ReadOperation, "audit/not-an-id")
+	_, err := b.HandleRequest(context.Background(), req)
+
+	assert.EqualError(t, err, `invalid audit record id "not-an-id"`)
+}
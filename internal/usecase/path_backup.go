@@ -0,0 +1,296 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const operationBackup = "backup"
+
+func pathBackup(b *Backend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "backup_signing_key",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.readBackupSigningKey,
+				},
+			},
+			HelpSynopsis: "Return the public key that verifies the backups made by this mount.",
+			HelpDescription: `
+
+    GET - return the PEM encoded RSA public key the backups of this mount are signed with. Add it to
+    the trustedBackupKeys of the mounts that restore them.
+
+    `,
+		},
+		{
+			Pattern: "backup/" + framework.GenericNameRegex("name"),
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.backupKeyManager,
+				},
+			},
+			HelpSynopsis: "Back up a key-manager as an encrypted blob for another mount of this plugin.",
+			HelpDescription: `
+
+    POST - return the key-manager, its keys, settings and attached signing policies encrypted to the
+    wrapping key of the target mount (read from its wrapping_key path). Without wrappingKey the backup
+    is encrypted to the wrapping key of this mount. Backing up to another mount exports the keys, so
+    the key-manager has to be exportable; in both cases its signing policies have to allow the backup
+    operation. The backup is signed with the backup signing key of this mount.
+
+    `,
+			Fields: map[string]*framework.FieldSchema{
+				"name": {Type: framework.TypeString},
+				"wrappingKey": {
+					Type:        framework.TypeString,
+					Description: "(optional) The PEM encoded wrapping key of the mount the backup will be restored to.",
+				},
+			},
+		},
+		{
+			Pattern: "restore/" + framework.GenericNameRegex("name"),
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.restoreKeyManager,
+				},
+			},
+			HelpSynopsis: "Restore a key-manager from a backup encrypted to this mount.",
+			HelpDescription: `
+
+    POST - check that the backup was signed by this mount or one of the trustedBackupKeys of its config,
+    decrypt it with the wrapping key of this mount and create the key-manager under the given name,
+    together with its signing policies. An existing key-manager, or an existing policy with other
+    rules than the backed up one, is only replaced with force.
+
+    `,
+			Fields: map[string]*framework.FieldSchema{
+				"name": {Type: framework.TypeString},
+				"backup": {
+					Type:        framework.TypeString,
+					Description: "The backup returned by backup/<name>.",
+				},
+				"force": {
+					Type:        framework.TypeBool,
+					Description: "(optional, default: false) Replace the key-manager and its signing policies if they already exist.",
+				},
+			},
+		},
+	}
+}
+
+func (b *Backend) readBackupSigningKey(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	signingKey, err := b.retrieveMountKey(ctx, req, backupSigningKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := encodePublicKeyPEM(&signingKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"public_key": publicKey,
+		},
+	}, nil
+}
+
+func (b *Backend) backupKeyManager(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	serviceName, ok := data.Get("name").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	wrappingKeyInput, ok := data.Get("wrappingKey").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	keyManager, err := b.retrieveKeyManager(ctx, req, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if keyManager == nil {
+		return nil, fmt.Errorf("keyManager does not exist")
+	}
+
+	if err = b.checkIdentity(keyManager, req.EntityID); err != nil {
+		return nil, err
+	}
+
+	ownKey, err := b.retrieveWrappingKey(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	wrappingKey := &ownKey.PublicKey
+	if wrappingKeyInput != "" {
+		if wrappingKey, err = parsePublicKeyPEM(wrappingKeyInput); err != nil {
+			return nil, err
+		}
+		// only this mount can open a backup to its own wrapping key, any other key exports the keys
+		if !wrappingKey.Equal(&ownKey.PublicKey) && !keyManager.Exportable {
+			return nil, fmt.Errorf("keyManager %s is not exportable", serviceName)
+		}
+	}
+
+	if _, err = b.enforcePolicies(ctx, req, keyManager, &policyInput{
+		operation:   operationBackup,
+		serviceName: serviceName,
+		entityID:    req.EntityID,
+	}); err != nil {
+		return nil, err
+	}
+
+	// session keys are destroyed with their lease, a restored copy would outlive it
+	keyManager.KeyPairs = keyManager.durableKeyPairs()
+	backup := &Backup{KeyManager: keyManager}
+	for _, name := range keyManager.Policies {
+		policy, err := b.retrievePolicy(ctx, req, name)
+		if err != nil {
+			return nil, err
+		}
+		if policy != nil {
+			backup.Policies = append(backup.Policies, policy)
+		}
+	}
+
+	signingKey, err := b.retrieveMountKey(ctx, req, backupSigningKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	blob, err := sealBackup(backup, wrappingKey, signingKey)
+	if err != nil {
+		b.Logger().Error("Failed to encrypt the backup", "service_name", serviceName, "error", err)
+		return nil, err
+	}
+
+	b.Logger().Info("Key-manager backed up", "service_name", serviceName, "entity_id", req.EntityID)
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"backup": blob,
+		},
+	}, nil
+}
+
+func (b *Backend) restoreKeyManager(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	serviceName, ok := data.Get("name").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	blob, ok := data.Get("backup").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	force, ok := data.Get("force").(bool)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	b.keyManagerLock.Lock()
+	defer b.keyManagerLock.Unlock()
+
+	existing, err := b.retrieveKeyManager(ctx, req, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && !force {
+		return nil, fmt.Errorf("keyManager %s already exists, set force to replace it", serviceName)
+	}
+
+	wrappingKey, err := b.retrieveWrappingKey(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := b.retrieveConfig(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	trustedKeys, err := parsePublicKeysPEM("trustedBackupKeys", config.TrustedBackupKeys)
+	if err != nil {
+		return nil, err
+	}
+	signingKey, err := b.retrieveMountKey(ctx, req, backupSigningKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	backup, err := openBackup(blob, wrappingKey, append(trustedKeys, &signingKey.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+
+	// the restored key-manager has to run under the rules it was backed up with
+	for _, policy := range backup.Policies {
+		current, err := b.retrievePolicy(ctx, req, policy.Name)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil && !reflect.DeepEqual(current, policy) && !force {
+			return nil, fmt.Errorf("policy %s differs from the backed up policy, set force to replace it", policy.Name)
+		}
+		if err = b.validatePolicy(policy); err != nil {
+			return nil, err
+		}
+	}
+	for _, policy := range backup.Policies {
+		entry, _ := logical.StorageEntryJSON(fmt.Sprintf("policies/%s", policy.Name), policy)
+		if err = req.Storage.Put(ctx, entry); err != nil {
+			b.Logger().Error("Failed to save the policy to storage", "name", policy.Name, "error", err)
+			return nil, err
+		}
+		b.forgetPolicy(policy.Name)
+	}
+
+	keyManager := backup.KeyManager
+	keyManager.ServiceName = serviceName
+	if err = b.saveKeyManager(ctx, req, keyManager); err != nil {
+		return nil, err
+	}
+	if err = savePoolIndex(ctx, req.Storage, keyManager); err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		b.Logger().Warn("Key-manager replaced by a restored backup", "service_name", serviceName, "entity_id", req.EntityID)
+	} else {
+		b.Logger().Info("Key-manager restored", "service_name", serviceName, "entity_id", req.EntityID)
+	}
+
+	addresses := make([]string, len(keyManager.KeyPairs))
+	for i := range keyManager.KeyPairs {
+		addresses[i] = keyManager.KeyPairs[i].Address
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"service_name": keyManager.ServiceName,
+			"addresses":    addresses,
+			"policies":     keyManager.Policies,
+		},
+	}, nil
+}
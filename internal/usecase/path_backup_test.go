@@ -0,0 +1,233 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackend_backupRestore(t *testing.T) {
+	defer func(bits int) { wrappingKeyBits = bits }(wrappingKeyBits)
+	wrappingKeyBits = 2048
+
+	primary, _ := newTestBackend(t)
+	secondary, _ := newTestBackend(t)
+
+	const (
+		treasurySvc = "treasury-service"
+		address     = "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704"
+	)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	primaryStorage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": treasurySvc,
+		"privateKey":  "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1",
+	}
+	_, err := primary.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "policies/hashes-only")
+	req.Storage = primaryStorage
+	req.Data = map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{
+				"expression": `request.operation in ["sign", "backup"]`,
+				"effect":     "allow",
+			},
+		},
+	}
+	_, err = primary.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+treasurySvc+"/config")
+	req.Storage = primaryStorage
+	req.Data = map[string]interface{}{
+		"policies": "hashes-only",
+	}
+	_, err = primary.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	publicKey := func(b logical.Backend, storage logical.Storage, path string) string {
+		req := logical.TestRequest(t, logical.ReadOperation, path)
+		req.Storage = storage
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return resp.Data["public_key"].(string)
+	}
+	secondaryStorage := &logical.InmemStorage{}
+
+	// the backup is encrypted to the wrapping key of the secondary mount, which exports the keys
+	backupReq := logical.TestRequest(t, logical.UpdateOperation, "backup/"+treasurySvc)
+	backupReq.Storage = primaryStorage
+	backupReq.Data = map[string]interface{}{
+		"wrappingKey": publicKey(secondary, secondaryStorage, "wrapping_key"),
+	}
+	_, err = primary.HandleRequest(context.Background(), backupReq)
+	assert.EqualError(t, err, "keyManager "+treasurySvc+" is not exportable")
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+treasurySvc+"/config")
+	req.Storage = primaryStorage
+	req.Data = map[string]interface{}{
+		"exportable": true,
+	}
+	_, err = primary.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp, err := primary.HandleRequest(context.Background(), backupReq)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	backup := resp.Data["backup"].(string)
+
+	restore := func(b logical.Backend, storage logical.Storage, name, backup string) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "restore/"+name)
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"backup": backup,
+		}
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	_, err = restore(primary, primaryStorage, "copy", backup)
+	assert.EqualError(t, err, "backup was not encrypted for this mount")
+
+	// the secondary mount only restores backups signed by the mounts it trusts
+	_, err = restore(secondary, secondaryStorage, "dr-treasury", backup)
+	assert.EqualError(t, err, "backup is not signed by a trusted mount")
+
+	trust := func(key string) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "config")
+		req.Storage = secondaryStorage
+		req.Data = map[string]interface{}{
+			"trustedBackupKeys": []string{key},
+		}
+		if _, err := secondary.HandleRequest(context.Background(), req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// backups are signed with a key of their own, not with the wrapping key
+	trust(publicKey(primary, primaryStorage, "wrapping_key"))
+	_, err = restore(secondary, secondaryStorage, "dr-treasury", backup)
+	assert.EqualError(t, err, "backup is not signed by a trusted mount")
+
+	trust(publicKey(primary, primaryStorage, "backup_signing_key"))
+
+	raw, _ := base64.StdEncoding.DecodeString(backup)
+	var sealed sealedBackup
+	if err = json.Unmarshal(raw, &sealed); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	sealed.Ciphertext[0] ^= 0xff
+	tampered, _ := json.Marshal(&sealed)
+	_, err = restore(secondary, secondaryStorage, "dr-treasury", base64.StdEncoding.EncodeToString(tampered))
+	assert.EqualError(t, err, "backup is not signed by a trusted mount")
+
+	// a policy of the same name with other rules is only replaced with force
+	req = logical.TestRequest(t, logical.UpdateOperation, "policies/hashes-only")
+	req.Storage = secondaryStorage
+	req.Data = map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{"expression": `true`, "effect": "allow"},
+		},
+	}
+	_, err = secondary.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, err = restore(secondary, secondaryStorage, "dr-treasury", backup)
+	assert.EqualError(t, err, "policy hashes-only differs from the backed up policy, set force to replace it")
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "restore/dr-treasury")
+	req.Storage = secondaryStorage
+	req.Data = map[string]interface{}{
+		"backup": backup,
+		"force":  true,
+	}
+	resp, err = secondary.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, []string{address}, resp.Data["addresses"])
+	assert.Equal(t, []string{"hashes-only"}, resp.Data["policies"])
+
+	_, err = restore(secondary, secondaryStorage, "dr-treasury", backup)
+	assert.EqualError(t, err, "keyManager dr-treasury already exists, set force to replace it")
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "restore/dr-treasury")
+	req.Storage = secondaryStorage
+	req.Data = map[string]interface{}{
+		"backup": backup,
+		"force":  true,
+	}
+	_, err = secondary.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// the restored key-manager signs with the same key under the restored policies
+	sign := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, path)
+		req.Storage = secondaryStorage
+		req.Data = data
+		return secondary.HandleRequest(context.Background(), req)
+	}
+
+	resp, err = sign("key-managers/dr-treasury/sign", map[string]interface{}{
+		"hash":    "0xaf41db230000000000000000000000000000000000000000000000000000000000000023",
+		"address": address,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.NotEmpty(t, resp.Data["signature"])
+
+	_, err = sign("key-managers/dr-treasury/txn/sign", map[string]interface{}{
+		"address":  address,
+		"data":     "0x",
+		"to":       "0xf809410b0d6f047c603deb311979cd413e025a84",
+		"gas":      21000,
+		"nonce":    "0x0",
+		"gasPrice": 1,
+		"chainId":  "1",
+	})
+	assert.ErrorContains(t, err, "request denied by policy hashes-only")
+}
+
+func TestBackend_backupKeyManagerFailure1(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": "treasury-service",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "backup/treasury-service")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"wrappingKey": "not a key",
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+
+	assert.EqualError(t, err, "wrappingKey must be a PEM encoded public key")
+}
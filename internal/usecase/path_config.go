@@ -0,0 +1,268 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const configPath = "config"
+
+// defaultGasLimit is the gas limit of transactions that do not set one, unless the mount
+// configures another default.
+const defaultGasLimit = 90000
+
+// Config holds the mount-wide settings shared by every key-manager.
+type Config struct {
+	AllowedChainIDs  []string `json:"allowed_chain_ids"`
+	AllowUnprotected bool     `json:"allow_unprotected"`
+	WebhookURLs      []string `json:"webhook_urls"`
+	WebhookSecret    string   `json:"webhook_secret"`
+
+	EntityRateLimit *RateLimit `json:"entity_rate_limit,omitempty"`
+
+	DefaultChainID     string `json:"default_chain_id,omitempty"`
+	DefaultGasLimit    uint64 `json:"default_gas_limit,omitempty"`
+	DisableHashSigning bool   `json:"disable_hash_signing"`
+	LogSensitiveFields bool   `json:"log_sensitive_fields"`
+
+	TrustedBackupKeys []string `json:"trusted_backup_keys,omitempty"`
+}
+
+func (c *Config) gasLimit() uint64 {
+	if c.DefaultGasLimit == 0 {
+		return defaultGasLimit
+	}
+	return c.DefaultGasLimit
+}
+
+const redactedValue = "<redacted>"
+
+// redact returns the value to log for transaction or typed data, which is only logged when the
+// mount is configured to. Key material is never logged, it is always redactedValue.
+func (c *Config) redact(value string) string {
+	if c.LogSensitiveFields {
+		return value
+	}
+	return redactedValue
+}
+
+// redacted returns the value to log for transaction or typed data of the request, redacting it
+// when the config cannot be read.
+func (b *Backend) redacted(ctx context.Context, req *logical.Request, value string) string {
+	config, err := b.retrieveConfig(ctx, req)
+	if err != nil {
+		return redactedValue
+	}
+	return config.redact(value)
+}
+
+func pathConfig(b *Backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config",
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.readConfig,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.updateConfig,
+			},
+		},
+		HelpSynopsis: "Read or update the mount-wide configuration of the plugin backend.",
+		HelpDescription: `
+
+    GET - return the mount configuration
+    POST - update the mount configuration
+
+    `,
+		Fields: map[string]*framework.FieldSchema{
+			"allowedChainIds": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "(optional) Chain IDs transactions may be signed for. Empty allows every chain ID. Can be narrowed per key-manager.",
+			},
+			"allowUnprotected": {
+				Type:        framework.TypeBool,
+				Description: "(optional, default: false) Allow signing pre-EIP155 transactions without a chain ID, which can be replayed on every EVM chain. Key-managers have to allow them as well.",
+			},
+			"webhookUrls": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "(optional) URLs every signing decision is posted to. Empty disables webhooks.",
+			},
+			"webhookSecret": {
+				Type:        framework.TypeString,
+				Description: "(optional) Secret the webhook payloads are signed with (HMAC-SHA256) in the X-Eth-Signer-Signature header.",
+			},
+			"entityRateLimit": {
+				Type:        framework.TypeFloat,
+				Description: "(optional) Signing requests per second allowed per requesting entity across the mount. 0 disables the limit.",
+			},
+			"entityRateLimitBurst": {
+				Type:        framework.TypeInt,
+				Description: "(optional, default: entityRateLimit rounded up) Signing requests allowed at once per requesting entity.",
+			},
+			"defaultChainId": {
+				Type:        framework.TypeString,
+				Description: "(optional) Chain ID of transactions signed without a chainId. Empty signs them with the Homestead signer.",
+			},
+			"defaultGasLimit": {
+				Type:        framework.TypeInt,
+				Description: "(optional, default: 90000) Gas limit of transactions signed without gas.",
+			},
+			"allowHashSigning": {
+				Type:        framework.TypeBool,
+				Description: "(optional, default: true) Allow signing raw hashes, which cannot be inspected by policies.",
+			},
+			"logSensitiveFields": {
+				Type:        framework.TypeBool,
+				Description: "(optional, default: false) Include the transaction data and typed data of rejected requests in the server logs. It is redacted otherwise. Key material, such as private keys, mnemonics and keystores, is never logged.",
+			},
+			"trustedBackupKeys": {
+				Type:        framework.TypeStringSlice,
+				Description: "(optional) PEM encoded backup signing keys (read from backup_signing_key) of the mounts whose backups may be restored here, besides this mount.",
+			},
+		},
+	}
+}
+
+func (b *Backend) retrieveConfig(ctx context.Context, req *logical.Request) (*Config, error) {
+	return b.retrieveConfigFromStorage(ctx, req.Storage)
+}
+
+func (b *Backend) retrieveConfigFromStorage(ctx context.Context, storage logical.Storage) (*Config, error) {
+	entry, err := storage.Get(ctx, configPath)
+	if err != nil {
+		b.Logger().Error("Failed to retrieve the config", "error", err)
+		return nil, err
+	}
+
+	config := &Config{}
+	if entry == nil {
+		return config, nil
+	}
+
+	if err = entry.DecodeJSON(config); err != nil {
+		b.Logger().Error("Failed to decode config", "error", err)
+		return nil, err
+	}
+	return config, nil
+}
+
+func (b *Backend) readConfig(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	config, err := b.retrieveConfig(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"allowed_chain_ids":    config.AllowedChainIDs,
+			"allow_unprotected":    config.AllowUnprotected,
+			"webhook_urls":         config.WebhookURLs,
+			"webhook_secret_set":   config.WebhookSecret != "",
+			"entity_rate_limit":    config.EntityRateLimit.response(),
+			"default_chain_id":     config.DefaultChainID,
+			"default_gas_limit":    config.gasLimit(),
+			"allow_hash_signing":   !config.DisableHashSigning,
+			"log_sensitive_fields": config.LogSensitiveFields,
+			"trusted_backup_keys":  config.TrustedBackupKeys,
+		},
+	}, nil
+}
+
+func (b *Backend) updateConfig(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	config, err := b.retrieveConfig(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if raw, ok := data.GetOk("allowedChainIds"); ok {
+		config.AllowedChainIDs, err = parseChainIDs(raw.([]string))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if raw, ok := data.GetOk("allowUnprotected"); ok {
+		config.AllowUnprotected = raw.(bool)
+	}
+
+	if raw, ok := data.GetOk("webhookUrls"); ok {
+		config.WebhookURLs, err = parseWebhookURLs(raw.([]string))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if raw, ok := data.GetOk("webhookSecret"); ok {
+		config.WebhookSecret = raw.(string)
+	}
+
+	if raw, ok := data.GetOk("defaultChainId"); ok {
+		config.DefaultChainID = ""
+		if raw.(string) != "" {
+			chainIDs, err := parseChainIDs([]string{raw.(string)})
+			if err != nil {
+				return nil, err
+			}
+			config.DefaultChainID = chainIDs[0]
+		}
+	}
+
+	if raw, ok := data.GetOk("defaultGasLimit"); ok {
+		if raw.(int) < 0 {
+			return nil, fmt.Errorf("defaultGasLimit cannot be negative")
+		}
+		config.DefaultGasLimit = uint64(raw.(int))
+	}
+
+	if raw, ok := data.GetOk("allowHashSigning"); ok {
+		config.DisableHashSigning = !raw.(bool)
+	}
+
+	if raw, ok := data.GetOk("logSensitiveFields"); ok {
+		config.LogSensitiveFields = raw.(bool)
+	}
+
+	if raw, ok := data.GetOk("trustedBackupKeys"); ok {
+		if _, err = parsePublicKeysPEM("trustedBackupKeys", raw.([]string)); err != nil {
+			return nil, err
+		}
+		config.TrustedBackupKeys = raw.([]string)
+	}
+
+	config.EntityRateLimit, err = parseRateLimit(data, config.EntityRateLimit, "entityRateLimit", "entityRateLimitBurst")
+	if err != nil {
+		return nil, err
+	}
+
+	entry, _ := logical.StorageEntryJSON(configPath, config)
+	if err = req.Storage.Put(ctx, entry); err != nil {
+		b.Logger().Error("Failed to save the config to storage", "error", err)
+		return nil, err
+	}
+
+	return b.readConfig(ctx, req, data)
+}
+
+// parseChainIDs validates the chain IDs and normalizes them to their decimal form.
+func parseChainIDs(input []string) ([]string, error) {
+	chainIDs := make([]string, 0, len(input))
+	for _, raw := range input {
+		chainID := validNumber(raw)
+		if chainID == nil || chainID.Sign() == 0 {
+			return nil, fmt.Errorf("invalid chainId %q", raw)
+		}
+		chainIDs = append(chainIDs, chainID.String())
+	}
+	return chainIDs, nil
+}
@@ -0,0 +1,493 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackend_config(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "config")
+	storage := req.Storage
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, false, resp.Data["allow_unprotected"])
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "config")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"allowedChainIds": []string{"1", "10"},
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "config")
+	req.Storage = storage
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, []string{"1", "10"}, resp.Data["allowed_chain_ids"])
+	assert.Equal(t, false, resp.Data["allow_unprotected"])
+}
+
+func TestBackend_configFailure1(t *testing.T) {
+	b, _ := newTestBackend(t)
+	req := logical.TestRequest(t, logical.UpdateOperation, "config")
+	req.Data = map[string]interface{}{
+		"allowedChainIds": []string{"0"},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+
+	assert.Equal(t, "invalid chainId \"0\"", err.Error())
+}
+
+func TestBackend_configFailure2(t *testing.T) {
+	b, _ := newTestBackend(t)
+	req := logical.TestRequest(t, logical.ReadOperation, "config")
+	sm := NewStorageMock(0, 0, 0, 0)
+	req.Storage = sm
+	_, err := b.HandleRequest(context.Background(), req)
+
+	assert.Equal(t, "failed to get", err.Error())
+}
+
+func TestBackend_webhooks(t *testing.T) {
+	defer func(backoff time.Duration) { webhookRetryBackoff = backoff }(webhookRetryBackoff)
+	webhookRetryBackoff = 0
+
+	b, _ := newTestBackend(t)
+
+	type delivery struct {
+		signature string
+		body      []byte
+	}
+	received := make(chan delivery, 10)
+	monitor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- delivery{signature: r.Header.Get(webhookSignatureHeader), body: body}
+	}))
+	defer monitor.Close()
+
+	// the second webhook is down for its first attempt
+	attempts := make(chan int, 10)
+	calls := 0
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		attempts <- calls
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer flaky.Close()
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "config")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"webhookUrls":   []string{monitor.URL, flaky.URL},
+		"webhookSecret": "s3cret",
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, []string{monitor.URL, flaky.URL}, resp.Data["webhook_urls"])
+	assert.Equal(t, true, resp.Data["webhook_secret_set"])
+	assert.Nil(t, resp.Data["webhook_secret"])
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"serviceName": "treasury",
+		"privateKey":  "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1",
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/treasury/sign")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"hash":    "0xaf41db230000000000000000000000000000000000000000000000000000000000000023",
+		"address": "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704",
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// the periodic function delivers the decisions in the outbox
+	periodic := func() {
+		req := logical.TestRequest(t, logical.RollbackOperation, "")
+		req.Storage = storage
+		if _, err := b.HandleRequest(context.Background(), req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	periodic()
+
+	var got delivery
+	select {
+	case got = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+	assert.Equal(t, "sha256="+webhookSignature("s3cret", got.body), got.signature)
+
+	var event WebhookEvent
+	if err = json.Unmarshal(got.body, &event); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, webhookEventType, event.Type)
+	assert.Equal(t, "treasury", event.Record.ServiceName)
+	assert.Equal(t, auditDecisionSigned, event.Record.Decision)
+
+	select {
+	case <-attempts:
+	case <-time.After(5 * time.Second):
+		t.Fatal("flaky webhook was not called")
+	}
+
+	// the next run retries the failed webhook only
+	periodic()
+	assert.Equal(t, 2, <-attempts)
+	assert.Len(t, received, 0)
+
+	outbox, err := storage.List(context.Background(), webhookOutboxPath)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Empty(t, outbox)
+}
+
+func TestBackend_configFailure3(t *testing.T) {
+	b, _ := newTestBackend(t)
+	req := logical.TestRequest(t, logical.UpdateOperation, "config")
+	req.Data = map[string]interface{}{
+		"webhookUrls": []string{"ftp://monitor.internal"},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+
+	assert.EqualError(t, err, `invalid webhook URL "ftp://monitor.internal"`)
+}
+
+func TestBackend_configDefaults(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "config")
+	storage := req.Storage
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, "", resp.Data["default_chain_id"])
+	assert.Equal(t, uint64(defaultGasLimit), resp.Data["default_gas_limit"])
+	assert.Equal(t, true, resp.Data["allow_hash_signing"])
+	assert.Equal(t, false, resp.Data["log_sensitive_fields"])
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"serviceName": "treasury",
+		"privateKey":  "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1",
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "config")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"defaultChainId":   "0x89",
+		"defaultGasLimit":  21000,
+		"allowHashSigning": false,
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, "137", resp.Data["default_chain_id"])
+	assert.Equal(t, uint64(21000), resp.Data["default_gas_limit"])
+	assert.Equal(t, false, resp.Data["allow_hash_signing"])
+
+	// the defaults fill in the fields the transaction leaves out
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/treasury/txn/sign")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"data":     "60fe47b10000000000000000000000000000000000000000000000000000000000000014",
+		"address":  "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704",
+		"to":       "0xf809410b0d6f047c603deb311979cd413e025a84",
+		"nonce":    "0x0",
+		"gasPrice": 0,
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, "eip155", resp.Data["signerType"])
+
+	rawTx, err := hexutil.Decode(resp.Data["signedTx"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := new(types.Transaction)
+	if err = tx.UnmarshalBinary(rawTx); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, big.NewInt(137), tx.ChainId())
+	assert.Equal(t, uint64(21000), tx.Gas())
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/treasury/sign")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"hash":    "0xaf41db230000000000000000000000000000000000000000000000000000000000000023",
+		"address": "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704",
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	assert.EqualError(t, err, "signing raw hashes is disabled on this mount")
+}
+
+func TestBackend_webhooksDeliveredOnce(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const decisions = 20
+
+	var mu sync.Mutex
+	received := make(map[string]int)
+	monitor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		received[r.Header.Get(webhookEventIDHeader)]++
+	}))
+	defer monitor.Close()
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "config")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"webhookUrls": []string{monitor.URL},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"serviceName": "treasury",
+		"privateKey":  "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1",
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// a burst of decisions while the periodic function flushes the outbox as well
+	var wg sync.WaitGroup
+	for i := 0; i < decisions; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/treasury/sign")
+			req.Storage = storage
+			req.Data = map[string]interface{}{
+				"hash":    "0xaf41db230000000000000000000000000000000000000000000000000000000000000023",
+				"address": "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704",
+			}
+			_, _ = b.HandleRequest(context.Background(), req)
+		}()
+		go func() {
+			defer wg.Done()
+			req := logical.TestRequest(t, logical.RollbackOperation, "")
+			req.Storage = storage
+			_, _ = b.HandleRequest(context.Background(), req)
+		}()
+	}
+	wg.Wait()
+
+	req = logical.TestRequest(t, logical.RollbackOperation, "")
+	req.Storage = storage
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	outbox, err := storage.List(context.Background(), webhookOutboxPath)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Empty(t, outbox)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, received, decisions)
+	for id, n := range received {
+		assert.Equal(t, 1, n, "event %s", id)
+	}
+}
+
+func TestBackend_webhooksPerformanceStandby(t *testing.T) {
+	calls := 0
+	monitor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer monitor.Close()
+
+	system := &logical.StaticSystemView{}
+	b, err := Factory(context.Background(), &logical.BackendConfig{
+		System:      system,
+		StorageView: &logical.InmemStorage{},
+		BackendUUID: "test",
+	})
+	if err != nil {
+		t.Fatalf("unable to create backend: %v", err)
+	}
+	storage := &logical.InmemStorage{}
+
+	update := func(path string, data map[string]interface{}) {
+		req := logical.TestRequest(t, logical.UpdateOperation, path)
+		req.Storage = storage
+		req.Data = data
+		if _, err := b.HandleRequest(context.Background(), req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	update("config", map[string]interface{}{"webhookUrls": []string{monitor.URL}})
+	update("key-managers", map[string]interface{}{
+		"serviceName": "treasury",
+		"privateKey":  "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1",
+	})
+
+	// a performance standby neither queues nor delivers webhooks
+	system.ReplicationStateVal = consts.ReplicationPerformanceStandby
+	update("key-managers/treasury/sign", map[string]interface{}{
+		"hash":    "0xaf41db230000000000000000000000000000000000000000000000000000000000000023",
+		"address": "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704",
+	})
+
+	outbox, err := storage.List(context.Background(), webhookOutboxPath)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Empty(t, outbox)
+
+	req := logical.TestRequest(t, logical.RollbackOperation, "")
+	req.Storage = storage
+	if _, err = b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, 0, calls)
+}
+
+// syncBuffer collects the log output of the backend.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.String()
+}
+
+func TestBackend_configLogSensitiveFields(t *testing.T) {
+	var logs syncBuffer
+	b, err := Factory(context.Background(), &logical.BackendConfig{
+		Logger:      hclog.New(&hclog.LoggerOptions{Output: &logs, Level: hclog.Trace}),
+		System:      &logical.StaticSystemView{},
+		StorageView: &logical.InmemStorage{},
+		BackendUUID: "test",
+	})
+	if err != nil {
+		t.Fatalf("unable to create backend: %v", err)
+	}
+	storage := &logical.InmemStorage{}
+
+	const (
+		privateKey = "0xnot-a-private-key"
+		mnemonic   = "secret words that are not a mnemonic"
+		memo       = "secret-memo"
+	)
+
+	rejected := func() {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"serviceName": "treasury",
+			"privateKey":  privateKey,
+		}
+		_, err := b.HandleRequest(context.Background(), req)
+		assert.Error(t, err)
+
+		req = logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"serviceName": "deposits",
+			"mnemonic":    mnemonic,
+		}
+		_, err = b.HandleRequest(context.Background(), req)
+		assert.EqualError(t, err, "invalid BIP-39 mnemonic")
+
+		req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/treasury/typed-data/sign")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"typedData": map[string]interface{}{
+				"primaryType": "Mail",
+				"message":     map[string]interface{}{"memo": memo},
+			},
+		}
+		_, err = b.HandleRequest(context.Background(), req)
+		assert.ErrorContains(t, err, "invalid typed data")
+	}
+
+	rejected()
+	assert.Contains(t, logs.String(), redactedValue)
+	for _, sensitive := range []string{privateKey, mnemonic, memo} {
+		assert.NotContains(t, logs.String(), sensitive)
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "config")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"logSensitiveFields": true,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// key material stays redacted, only the typed data is logged
+	rejected()
+	assert.Contains(t, logs.String(), memo)
+	for _, sensitive := range []string{privateKey, mnemonic} {
+		assert.NotContains(t, logs.String(), sensitive)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -31,7 +32,7 @@ func pathCreateAndList(b *Backend) *framework.Path {
     LIST - list all keyManagers
 
     `,
-		Fields: map[string]*framework.FieldSchema{
+		Fields: withListFields(map[string]*framework.FieldSchema{
 			"serviceName": {
 				Type:        framework.TypeString,
 				Description: "The service that is the owner of the private-key",
@@ -42,7 +43,36 @@ func pathCreateAndList(b *Backend) *framework.Path {
 				Description: "(Optional, default random key) Hex string for the private key (32-byte or 64-char long). If present, the request will import the given key instead of generating a new key.",
 				Default:     "",
 			},
-		},
+			"hd": {
+				Type:        framework.TypeBool,
+				Description: "(Optional, default: false) Create an HD key-manager whose addresses are derived from a BIP-39 seed along m/44'/60'/0'/0/i. A new mnemonic is generated unless one is imported.",
+			},
+			"mnemonic": {
+				Type:        framework.TypeString,
+				Description: "(Optional) BIP-39 mnemonic to import as the seed of a new HD key-manager.",
+			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "(Optional) BIP-39 passphrase of the mnemonic.",
+			},
+			"ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "(Optional) How long the new key may sign, after which it expires.",
+			},
+			"maxSignatures": {
+				Type:        framework.TypeInt,
+				Description: "(Optional) How many signatures the new key may make, after which it expires.",
+			},
+			"count": {
+				Type:        framework.TypeInt,
+				Description: "(Optional, default: 1) Number of keys to generate, at most 1000. All new addresses are returned.",
+				Default:     1,
+			},
+			"labels": {
+				Type:        framework.TypeKVPairs,
+				Description: "(Optional) key=value labels of the new keys to filter the addresses of the key-manager by.",
+			},
+		}),
 	}
 }
 
@@ -51,13 +81,39 @@ func (b *Backend) listKeyManagers(
 	req *logical.Request,
 	data *framework.FieldData,
 ) (*logical.Response, error) {
+	filter, err := newListFilter(data)
+	if err != nil {
+		return nil, err
+	}
+
 	vals, err := req.Storage.List(ctx, "key-managers/")
 	if err != nil {
 		b.Logger().Error("Failed to retrieve the list of keyManagers", "error", err)
 		return nil, err
 	}
 
-	return logical.ListResponse(vals), nil
+	names := filter.page(vals)
+	keys := make([]string, 0)
+	keyInfo := make(map[string]interface{})
+	for i, name := range names {
+		if filter.full(len(keys)) {
+			resp := logical.ListResponseWithInfo(keys, keyInfo)
+			resp.Data["next_after"] = names[i-1]
+			return resp, nil
+		}
+
+		info, err := b.retrieveKeyManagerInfo(ctx, req, name)
+		if err != nil {
+			return nil, err
+		}
+		if info == nil || !filter.matchLabels(info.Labels) {
+			continue
+		}
+
+		keys = append(keys, name)
+		keyInfo[name] = info.response()
+	}
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
 }
 
 func (b *Backend) createKeyManager(
@@ -75,11 +131,36 @@ func (b *Backend) createKeyManager(
 		return nil, errInvalidType
 	}
 
+	b.keyManagerLock.Lock()
+	defer b.keyManagerLock.Unlock()
+
 	keyManager, err := b.retrieveKeyManager(ctx, req, serviceInput)
 	if err != nil {
 		return nil, err
 	}
 
+	options, err := newKeyOptions(time.Duration(data.Get("ttl").(int))*time.Second, data.Get("maxSignatures").(int),
+		data.Get("labels").(map[string]string))
+	if err != nil {
+		return nil, err
+	}
+
+	count := data.Get("count").(int)
+	if count < 1 || count > maxKeyBatch {
+		return nil, fmt.Errorf("count must be between 1 and %d", maxKeyBatch)
+	}
+	if keyInput != "" && count > 1 {
+		return nil, errors.New("count cannot be combined with privateKey")
+	}
+
+	mnemonic := data.Get("mnemonic").(string)
+	if data.Get("hd").(bool) || mnemonic != "" || (keyManager != nil && keyManager.Seed != "") {
+		if keyInput != "" {
+			return nil, errors.New("privateKey cannot be imported into an HD key-manager")
+		}
+		return b.createHDKeyPairs(ctx, req, serviceInput, keyManager, mnemonic, data.Get("passphrase").(string), count, options)
+	}
+
 	if keyManager == nil {
 		keyManager = &KeyManager{
 			ServiceName: serviceInput,
@@ -94,7 +175,7 @@ func (b *Backend) createKeyManager(
 
 		key := re.FindString(keyInput)
 		if key == "" {
-			b.Logger().Error("Input private key did not parse successfully", "privateKey", keyInput)
+			b.Logger().Error("Input private key did not parse successfully", "privateKey", redactedValue)
 			return nil, fmt.Errorf("privateKey must be a 32-byte hexidecimal string")
 		}
 
@@ -123,22 +204,104 @@ func (b *Backend) createKeyManager(
 		PublicKey:  common.Bytes2Hex(publicKeyBytes),
 		Address:    crypto.PubkeyToAddress(*publicKeyECDSA).Hex(),
 	}
+	options.apply(keyPair)
 
 	keyManager.KeyPairs = append(keyManager.KeyPairs, keyPair)
 
-	policyPath := fmt.Sprintf("key-managers/%s", serviceInput)
-	entry, _ := logical.StorageEntryJSON(policyPath, keyManager)
-	err = req.Storage.Put(ctx, entry)
+	// a single write for the whole batch instead of one per key
+	keyPairs, err := keyManager.generateKeyPairs(count-1, options)
 	if err != nil {
-		b.Logger().Error("Failed to save the new keyManager to storage", "error", err)
 		return nil, err
 	}
+	keyPairs = append([]*KeyPair{keyPair}, keyPairs...)
 
-	return &logical.Response{
+	if err = b.saveKeyManager(ctx, req, keyManager); err != nil {
+		return nil, err
+	}
+
+	emitKeyManagerMetric(metricKeyManagerCreated, keyManager.ServiceName)
+
+	resp := &logical.Response{
 		Data: map[string]interface{}{
 			"service_name": keyManager.ServiceName,
 			"address":      keyPair.Address,
 			"public_key":   keyPair.PublicKey,
 		},
-	}, nil
+	}
+	if count > 1 {
+		resp.Data["addresses"] = addressesOf(keyPairs)
+	}
+	return resp, nil
+}
+
+// createHDKeyPairs derives the next addresses of an HD key-manager, creating the key-manager and its
+// seed first when it does not exist yet. A generated mnemonic is returned once so that it can be
+// backed up offline.
+func (b *Backend) createHDKeyPairs(
+	ctx context.Context,
+	req *logical.Request,
+	serviceName string,
+	keyManager *KeyManager,
+	mnemonic string,
+	passphrase string,
+	count int,
+	options *keyOptions,
+) (*logical.Response, error) {
+	var generated bool
+	if keyManager == nil {
+		keyManager = &KeyManager{
+			ServiceName: serviceName,
+		}
+	} else if keyManager.Seed == "" {
+		return nil, fmt.Errorf("keyManager %s holds standalone keys and cannot derive HD keys", serviceName)
+	} else if mnemonic != "" {
+		return nil, fmt.Errorf("keyManager %s already has a seed", serviceName)
+	}
+
+	if keyManager.Seed == "" {
+		var err error
+		if mnemonic == "" {
+			if mnemonic, err = newMnemonic(); err != nil {
+				return nil, err
+			}
+			generated = true
+		}
+
+		seed, err := seedFromMnemonic(mnemonic, passphrase)
+		if err != nil {
+			b.Logger().Error("Input mnemonic did not parse successfully", "service_name", serviceName,
+				"mnemonic", redactedValue)
+			return nil, err
+		}
+		keyManager.Seed = common.Bytes2Hex(seed)
+	}
+
+	keyPairs, err := keyManager.generateKeyPairs(count, options)
+	if err != nil {
+		b.Logger().Error("Failed to derive the HD key pair", "service_name", serviceName, "error", err)
+		return nil, err
+	}
+	keyPair := keyPairs[0]
+
+	if err = b.saveKeyManager(ctx, req, keyManager); err != nil {
+		return nil, err
+	}
+
+	emitKeyManagerMetric(metricKeyManagerCreated, keyManager.ServiceName)
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"service_name":    keyManager.ServiceName,
+			"address":         keyPair.Address,
+			"public_key":      keyPair.PublicKey,
+			"derivation_path": keyPair.DerivationPath,
+		},
+	}
+	if count > 1 {
+		resp.Data["addresses"] = addressesOf(keyPairs)
+	}
+	if generated {
+		resp.Data["mnemonic"] = mnemonic
+	}
+	return resp, nil
 }
@@ -2,8 +2,12 @@ package usecase
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/stretchr/testify/assert"
 )
@@ -44,11 +48,10 @@ func TestBackend_createKeyManager(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 
-	expectedList := &logical.Response{
-		Data: map[string]interface{}{
-			"keys": []string{testSvc1, testSvc2},
-		},
-	}
+	expectedList := logical.ListResponseWithInfo([]string{testSvc1, testSvc2}, map[string]interface{}{
+		testSvc1: map[string]interface{}{"key_count": 1, "hd": false, "labels": map[string]string(nil)},
+		testSvc2: map[string]interface{}{"key_count": 1, "hd": false, "labels": map[string]string(nil)},
+	})
 
 	assert.Equal(t, expectedList, resp)
 
@@ -66,6 +69,12 @@ func TestBackend_createKeyManager(t *testing.T) {
 			"addresses": []string{
 				"0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704",
 			},
+			"key_info": map[string]interface{}{
+				"0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704": map[string]interface{}{
+					"status":     "active",
+					"public_key": "045809f2cb46e0a05b7e535e765dc3c658d2a196170f80570900483a46c7875720a2a885656d77181d1107bee5b2f2758a5be3fe58037693c10e7adf16746367bc",
+				},
+			},
 		},
 	}
 
@@ -120,3 +129,377 @@ func TestBackend_listPoliciesFailure1(t *testing.T) {
 
 	assert.Equal(t, "failed to list", err.Error())
 }
+
+func TestBackend_createHDKeyManager(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const (
+		depositsSvc = "deposits-service"
+		mnemonic    = "test test test test test test test test test test test junk"
+	)
+
+	create := func(data map[string]interface{}) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+		req.Data = data
+		return b.HandleRequest(context.Background(), req)
+	}
+	storage := logical.TestRequest(t, logical.UpdateOperation, "key-managers").Storage
+	createIn := func(data map[string]interface{}) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+		req.Storage = storage
+		req.Data = data
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	// addresses are derived along m/44'/60'/0'/0/i
+	resp, err := createIn(map[string]interface{}{
+		"serviceName": depositsSvc,
+		"mnemonic":    mnemonic,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266", resp.Data["address"])
+	assert.Equal(t, "m/44'/60'/0'/0/0", resp.Data["derivation_path"])
+	assert.Nil(t, resp.Data["mnemonic"])
+
+	resp, err = createIn(map[string]interface{}{
+		"serviceName": depositsSvc,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, "0x70997970C51812dc3A010C7d01b50e0d17dc79C8", resp.Data["address"])
+	assert.Equal(t, "m/44'/60'/0'/0/1", resp.Data["derivation_path"])
+
+	// only the seed and derivation paths are stored
+	km, err := (b.(*Backend)).retrieveKeyManager(context.Background(), &logical.Request{Storage: storage}, depositsSvc)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, keyPair := range km.KeyPairs {
+		assert.Empty(t, keyPair.PrivateKey)
+	}
+
+	// derived keys sign transactions
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+depositsSvc+"/txn/sign")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"address":  "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+		"data":     "0x",
+		"to":       "0xf809410b0d6f047c603deb311979cd413e025a84",
+		"gas":      21000,
+		"nonce":    "0x0",
+		"gasPrice": 1,
+		"chainId":  "1",
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	signedTx, err := hexutil.Decode(resp.Data["signedTx"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := new(types.Transaction)
+	if err = tx.UnmarshalBinary(signedTx); err != nil {
+		t.Fatal(err)
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "0x70997970C51812dc3A010C7d01b50e0d17dc79C8", sender.Hex())
+
+	_, err = createIn(map[string]interface{}{
+		"serviceName": depositsSvc,
+		"privateKey":  "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1",
+	})
+	assert.EqualError(t, err, "privateKey cannot be imported into an HD key-manager")
+
+	_, err = createIn(map[string]interface{}{
+		"serviceName": depositsSvc,
+		"mnemonic":    mnemonic,
+	})
+	assert.EqualError(t, err, "keyManager "+depositsSvc+" already has a seed")
+
+	// a generated mnemonic is returned once and regenerates the same addresses
+	resp, err = create(map[string]interface{}{
+		"serviceName": "generated-service",
+		"hd":          true,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	generated := resp.Data["mnemonic"].(string)
+	assert.Len(t, strings.Fields(generated), 24)
+
+	restored, err := create(map[string]interface{}{
+		"serviceName": "restored-service",
+		"mnemonic":    generated,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, resp.Data["address"], restored.Data["address"])
+}
+
+func TestBackend_createHDKeyManagerFailure1(t *testing.T) {
+	b, _ := newTestBackend(t)
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	req.Data = map[string]interface{}{
+		"serviceName": "deposits-service",
+		"mnemonic":    "test test test test test test test test test test test test",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+
+	assert.EqualError(t, err, "invalid BIP-39 mnemonic")
+}
+
+func TestBackend_createHDKeyManagerFailure2(t *testing.T) {
+	b, _ := newTestBackend(t)
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": "keeper-service",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"serviceName": "keeper-service",
+		"hd":          true,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+
+	assert.EqualError(t, err, "keyManager keeper-service holds standalone keys and cannot derive HD keys")
+}
+
+func TestBackend_createKeyManagerWithLimits(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const (
+		botSvc  = "trading-bot"
+		address = "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704"
+	)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName":   botSvc,
+		"privateKey":    "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1",
+		"maxSignatures": 2,
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// a second, short-lived key
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"serviceName": botSvc,
+		"ttl":         1,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	sessionAddress := resp.Data["address"].(string)
+
+	sign := func(address string) error {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+botSvc+"/sign")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"hash":    "0xaf41db230000000000000000000000000000000000000000000000000000000000000023",
+			"address": address,
+		}
+		_, err := b.HandleRequest(context.Background(), req)
+		return err
+	}
+
+	assert.NoError(t, sign(address))
+	assert.NoError(t, sign(sessionAddress))
+	assert.NoError(t, sign(address))
+	assert.EqualError(t, sign(address), "address "+address+" used all of its 2 signatures")
+
+	time.Sleep(time.Second)
+	assert.ErrorContains(t, sign(sessionAddress), "address "+sessionAddress+" expired at ")
+
+	req = logical.TestRequest(t, logical.ReadOperation, "key-managers/"+botSvc)
+	req.Storage = storage
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	keyInfo := resp.Data["key_info"].(map[string]interface{})
+	assert.Equal(t, "expired", keyInfo[address].(map[string]interface{})["status"])
+	assert.Equal(t, 2, keyInfo[address].(map[string]interface{})["signatures"])
+	assert.Equal(t, "expired", keyInfo[sessionAddress].(map[string]interface{})["status"])
+	assert.NotEmpty(t, keyInfo[sessionAddress].(map[string]interface{})["not_after"])
+}
+
+func TestBackend_listKeyManagersPaginated(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	for _, name := range []string{"payouts-eu", "payouts-us", "deposits", "payouts-apac"} {
+		req = logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"serviceName": name,
+		}
+		if _, err := b.HandleRequest(context.Background(), req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/payouts-us/config")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"labels": []string{"team=payments", "region=us"},
+	}
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	list := func(data map[string]interface{}) *logical.Response {
+		req := logical.TestRequest(t, logical.ListOperation, "key-managers")
+		req.Storage = storage
+		req.Data = data
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return resp
+	}
+
+	resp := list(map[string]interface{}{"prefix": "payouts-", "limit": 2})
+	assert.Equal(t, []string{"payouts-apac", "payouts-eu"}, resp.Data["keys"])
+	assert.Equal(t, "payouts-eu", resp.Data["next_after"])
+
+	resp = list(map[string]interface{}{"prefix": "payouts-", "limit": 2, "after": "payouts-eu"})
+	assert.Equal(t, []string{"payouts-us"}, resp.Data["keys"])
+	assert.Nil(t, resp.Data["next_after"])
+
+	resp = list(map[string]interface{}{"label": "team=payments"})
+	assert.Equal(t, []string{"payouts-us"}, resp.Data["keys"])
+	assert.Equal(t, map[string]string{"team": "payments", "region": "us"},
+		resp.Data["key_info"].(map[string]interface{})["payouts-us"].(map[string]interface{})["labels"])
+}
+
+func TestBackend_readKeyManagerPaginated(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const depositsSvc = "deposits"
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": depositsSvc,
+		"count":       5,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	addresses := resp.Data["addresses"].([]string)
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"serviceName": depositsSvc,
+		"labels":      "customer=42",
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	labelled := resp.Data["address"].(string)
+
+	read := func(data map[string]interface{}) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.ReadOperation, "key-managers/"+depositsSvc)
+		req.Storage = storage
+		req.Data = data
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	resp, err = read(map[string]interface{}{"limit": 2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, addresses[:2], resp.Data["addresses"])
+	assert.Equal(t, addresses[1], resp.Data["next_after"])
+
+	resp, err = read(map[string]interface{}{"limit": 3, "after": addresses[1]})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, addresses[2:], resp.Data["addresses"])
+	assert.Equal(t, addresses[4], resp.Data["next_after"])
+
+	resp, err = read(map[string]interface{}{"label": "customer=42"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, []string{labelled}, resp.Data["addresses"])
+
+	resp, err = read(map[string]interface{}{"prefix": strings.ToLower(addresses[3][:8])})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Contains(t, resp.Data["addresses"], addresses[3])
+
+	_, err = read(map[string]interface{}{"after": "0x0000000000000000000000000000000000000000"})
+	assert.EqualError(t, err, "address 0x0000000000000000000000000000000000000000 to list after does not exist")
+}
+
+func TestBackend_listKeyManagersInfo(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": "treasury",
+		"count":       2,
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// key-managers saved before the listing info was kept are decoded instead
+	legacy, _ := logical.StorageEntryJSON("key-managers/legacy", &KeyManager{
+		ServiceName: "legacy",
+		KeyPairs:    []*KeyPair{{Address: "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704"}},
+		Labels:      map[string]string{"team": "ops"},
+	})
+	if err = storage.Put(context.Background(), legacy); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// listing does not decode the key material of the key-manager
+	if err = storage.Put(context.Background(), &logical.StorageEntry{
+		Key:   "key-managers/treasury",
+		Value: []byte("not json"),
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.ListOperation, "key-managers")
+	req.Storage = storage
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, logical.ListResponseWithInfo([]string{"legacy", "treasury"}, map[string]interface{}{
+		"legacy":   map[string]interface{}{"key_count": 1, "hd": false, "labels": map[string]string{"team": "ops"}},
+		"treasury": map[string]interface{}{"key_count": 2, "hd": false, "labels": map[string]string(nil)},
+	}), resp)
+}
@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathDerive(b *Backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "key-managers/" + framework.GenericNameRegex("name") + "/derive",
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.derive,
+			},
+		},
+		HelpSynopsis: "Return the stable address an HD key-manager derives for an external identifier.",
+		HelpDescription: `
+
+    POST - derive the child key of the derivationId (a user ID, account UUID...) from the seed of the
+    key-manager and return its address. The same derivationId always maps to the same address, and
+    nothing is stored: signTx signs for it with derivationId in place of address.
+
+    `,
+		Fields: map[string]*framework.FieldSchema{
+			"name": {Type: framework.TypeString},
+			"derivationId": {
+				Type:        framework.TypeString,
+				Description: "The external identifier to derive the address for.",
+			},
+		},
+	}
+}
+
+func (b *Backend) derive(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	serviceName, ok := data.Get("name").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	derivationID, ok := data.Get("derivationId").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	keyManager, err := b.retrieveKeyManager(ctx, req, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if keyManager == nil {
+		return nil, fmt.Errorf("keyManager does not exist")
+	}
+
+	if err = b.checkIdentity(keyManager, req.EntityID); err != nil {
+		return nil, err
+	}
+
+	keyPair, err := keyManager.derivedKeyPair(derivationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"service_name":  keyManager.ServiceName,
+			"derivation_id": keyPair.DerivationID,
+			"address":       keyPair.Address,
+			"public_key":    keyPair.PublicKey,
+		},
+	}, nil
+}
@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackend_derive(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const custodySvc = "custody-service"
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": custodySvc,
+		"mnemonic":    "test test test test test test test test test test test junk",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	derive := func(derivationID string) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+custodySvc+"/derive")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"derivationId": derivationID,
+		}
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	first, err := derive("9b2f6c0e-5a4d-4d7e-9f61-0c1d2e3f4a5b")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	again, err := derive("9b2f6c0e-5a4d-4d7e-9f61-0c1d2e3f4a5b")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	other, err := derive("user-42")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	address := first.Data["address"].(string)
+	assert.Equal(t, address, again.Data["address"])
+	assert.NotEqual(t, address, other.Data["address"])
+
+	_, err = derive("")
+	assert.EqualError(t, err, "derivationId must be 1 to 256 characters long")
+
+	// derived addresses are not stored on the key-manager
+	req = logical.TestRequest(t, logical.ReadOperation, "key-managers/"+custodySvc)
+	req.Storage = storage
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, []string{"0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"}, resp.Data["addresses"])
+
+	signTx := func(data map[string]interface{}) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+custodySvc+"/txn/sign")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"data":     "0x",
+			"to":       "0xf809410b0d6f047c603deb311979cd413e025a84",
+			"gas":      21000,
+			"nonce":    "0x0",
+			"gasPrice": 1,
+			"chainId":  "1",
+		}
+		for k, v := range data {
+			req.Data[k] = v
+		}
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	resp, err = signTx(map[string]interface{}{
+		"derivationId": "9b2f6c0e-5a4d-4d7e-9f61-0c1d2e3f4a5b",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	signedTx, err := hexutil.Decode(resp.Data["signedTx"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := new(types.Transaction)
+	if err = tx.UnmarshalBinary(signedTx); err != nil {
+		t.Fatal(err)
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, address, sender.Hex())
+
+	_, err = signTx(map[string]interface{}{
+		"derivationId": "user-42",
+		"address":      address,
+	})
+	assert.EqualError(t, err, "address "+address+" does not belong to derivationId user-42")
+}
+
+func TestBackend_deriveFailure1(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": "keeper-service",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/keeper-service/derive")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"derivationId": "user-42",
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+
+	assert.EqualError(t, err, "keyManager keeper-service has no seed to derive keys from, create it with hd=true")
+}
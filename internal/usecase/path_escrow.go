@@ -0,0 +1,235 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/shamir"
+)
+
+const operationEscrow = "escrow"
+
+func pathEscrow(b *Backend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "key-managers/" + framework.GenericNameRegex("name") + "/escrow",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.escrowKeyManager,
+				},
+			},
+			HelpSynopsis: "Split the key material of a key-manager into PGP encrypted Shamir shares.",
+			HelpDescription: `
+
+    POST - split the keys (or HD seed) of the key-manager into one Shamir share per PGP key, any
+    threshold of which recover it. Every share is encrypted to its PGP key, and every PGP key has to
+    be different. The key-manager settings (purposes, policies, chain IDs, identity bindings, rate
+    limits, approvals and signing delay) are escrowed with the keys and restored on recovery. The
+    key-manager has to be configured as exportable, and its signing policies have to allow the
+    escrow operation.
+
+    `,
+			Fields: map[string]*framework.FieldSchema{
+				"name": {Type: framework.TypeString},
+				"pgpKeys": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "The PGP public keys of the share holders, armored or base64 encoded binary. One share is created per key.",
+				},
+				"threshold": {
+					Type:        framework.TypeInt,
+					Description: "The number of shares required to recover the key material, at least 2.",
+				},
+			},
+		},
+		{
+			Pattern: "key-managers/" + framework.GenericNameRegex("name") + "/recover",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.recoverKeyManager,
+				},
+			},
+			HelpSynopsis: "Recreate a key-manager from decrypted escrow shares.",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {Type: framework.TypeString},
+				"shares": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "At least threshold decrypted escrow shares, base64 encoded as they were decrypted.",
+				},
+			},
+		},
+	}
+}
+
+func (b *Backend) escrowKeyManager(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	serviceName, ok := data.Get("name").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	pgpKeys, ok := data.Get("pgpKeys").([]string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	threshold, ok := data.Get("threshold").(int)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	keyManager, err := b.retrieveKeyManager(ctx, req, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if keyManager == nil {
+		return nil, fmt.Errorf("keyManager does not exist")
+	}
+
+	if err = b.checkIdentity(keyManager, req.EntityID); err != nil {
+		return nil, err
+	}
+
+	if !keyManager.Exportable {
+		return nil, fmt.Errorf("keyManager %s is not exportable", serviceName)
+	}
+
+	if _, err = b.enforcePolicies(ctx, req, keyManager, &policyInput{
+		operation:   operationEscrow,
+		serviceName: serviceName,
+		entityID:    req.EntityID,
+	}); err != nil {
+		return nil, err
+	}
+
+	// every share holder must be a different person, or one of them could reach the threshold alone
+	entities := make([]*openpgp.Entity, len(pgpKeys))
+	fingerprints := make([]string, len(pgpKeys))
+	seen := make(map[string]int, len(pgpKeys))
+	for i, pgpKey := range pgpKeys {
+		if entities[i], err = parsePGPKey(pgpKey); err != nil {
+			return nil, fmt.Errorf("pgpKeys[%d]: %w", i, err)
+		}
+		fingerprints[i] = pgpFingerprint(entities[i])
+		if j, ok := seen[fingerprints[i]]; ok {
+			return nil, fmt.Errorf("pgpKeys[%d] and pgpKeys[%d] are the same PGP key", j, i)
+		}
+		seen[fingerprints[i]] = i
+	}
+
+	secret, err := json.Marshal(&EscrowSecret{
+		ServiceName: keyManager.ServiceName,
+		Seed:        keyManager.Seed,
+		NextIndex:   keyManager.NextIndex,
+		KeyPairs:    keyManager.durableKeyPairs(),
+		Settings:    keyManager.settings(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret)
+
+	shares, err := shamir.Split(secret, len(pgpKeys), threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted := make([]string, len(shares))
+	for i, entity := range entities {
+		if encrypted[i], err = encryptShare(shares[i], entity); err != nil {
+			b.Logger().Error("Failed to encrypt the escrow share", "fingerprint", fingerprints[i], "error", err)
+			return nil, err
+		}
+		zeroBytes(shares[i])
+	}
+
+	b.Logger().Warn("Key material split into escrow shares", "service_name", serviceName,
+		"shares", len(shares), "threshold", threshold, "entity_id", req.EntityID)
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"service_name": keyManager.ServiceName,
+			"threshold":    threshold,
+			"shares":       encrypted,
+			"fingerprints": fingerprints,
+		},
+	}, nil
+}
+
+func (b *Backend) recoverKeyManager(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	serviceName, ok := data.Get("name").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	sharesInput, ok := data.Get("shares").([]string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	b.keyManagerLock.Lock()
+	defer b.keyManagerLock.Unlock()
+
+	existing, err := b.retrieveKeyManager(ctx, req, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("keyManager %s already exists", serviceName)
+	}
+
+	shares := make([][]byte, len(sharesInput))
+	for i, share := range sharesInput {
+		if shares[i], err = base64.StdEncoding.DecodeString(share); err != nil {
+			return nil, fmt.Errorf("shares[%d] must be base64 encoded", i)
+		}
+	}
+
+	secret, err := shamir.Combine(shares)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret)
+
+	var escrow EscrowSecret
+	if err = json.Unmarshal(secret, &escrow); err != nil || escrow.Settings == nil {
+		return nil, errors.New("shares do not recover a key-manager, too few or mismatched shares")
+	}
+
+	// the recovered key-manager keeps the signing restrictions it was escrowed with
+	keyManager := escrow.Settings
+	keyManager.ServiceName = serviceName
+	keyManager.Seed = escrow.Seed
+	keyManager.NextIndex = escrow.NextIndex
+	keyManager.KeyPairs = escrow.KeyPairs
+	if err = b.saveKeyManager(ctx, req, keyManager); err != nil {
+		return nil, err
+	}
+
+	b.Logger().Warn("Key-manager recovered from escrow shares", "service_name", serviceName,
+		"escrowed_service_name", escrow.ServiceName, "entity_id", req.EntityID)
+
+	addresses := make([]string, len(keyManager.KeyPairs))
+	for i := range keyManager.KeyPairs {
+		addresses[i] = keyManager.KeyPairs[i].Address
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"service_name": keyManager.ServiceName,
+			"addresses":    addresses,
+		},
+	}, nil
+}
@@ -0,0 +1,223 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackend_escrowRecover(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const (
+		hdSvc    = "hd-service"
+		mnemonic = "test test test test test test test test test test test junk"
+		address  = "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+	)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": hdSvc,
+		"hd":          true,
+		"mnemonic":    mnemonic,
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	holders := make([]*openpgp.Entity, 3)
+	pgpKeys := make([]string, len(holders))
+	for i := range holders {
+		holders[i], err = openpgp.NewEntity("holder", "", "holder@example.com",
+			&packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		var buf bytes.Buffer
+		if err = holders[i].Serialize(&buf); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		pgpKeys[i] = base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+
+	escrow := func() (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+hdSvc+"/escrow")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"pgpKeys":   pgpKeys,
+			"threshold": 2,
+		}
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	_, err = escrow()
+	assert.EqualError(t, err, "keyManager "+hdSvc+" is not exportable")
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+hdSvc+"/config")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"exportable": true,
+		"purposes":   "transaction",
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp, err := escrow()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, 2, resp.Data["threshold"])
+	assert.Equal(t, pgpFingerprint(holders[1]), resp.Data["fingerprints"].([]string)[1])
+
+	encrypted := resp.Data["shares"].([]string)
+	assert.Len(t, encrypted, 3)
+
+	// every holder decrypts only their own share
+	decrypt := func(i int) string {
+		raw, _ := base64.StdEncoding.DecodeString(encrypted[i])
+		md, err := openpgp.ReadMessage(bytes.NewReader(raw), openpgp.EntityList{holders[i]}, nil, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		share, err := io.ReadAll(md.UnverifiedBody)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return string(share)
+	}
+
+	recoverKeyManager := func(name string, shares ...string) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+name+"/recover")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"shares": shares,
+		}
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	_, err = recoverKeyManager("recovered", decrypt(0))
+	assert.EqualError(t, err, "less than two parts cannot be used to reconstruct the secret")
+
+	_, err = recoverKeyManager(hdSvc, decrypt(0), decrypt(2))
+	assert.EqualError(t, err, "keyManager "+hdSvc+" already exists")
+
+	resp, err = recoverKeyManager("recovered", decrypt(2), decrypt(0))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, []string{address}, resp.Data["addresses"])
+
+	// the recovered key-manager keeps the settings it was escrowed with
+	req = logical.TestRequest(t, logical.ReadOperation, "key-managers/recovered/config")
+	req.Storage = storage
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, []string{purposeTransaction}, resp.Data["purposes"])
+	assert.Equal(t, true, resp.Data["exportable"])
+
+	// the recovered key-manager keeps deriving from the escrowed seed
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"serviceName": "recovered",
+		"hd":          true,
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, "0x70997970C51812dc3A010C7d01b50e0d17dc79C8", resp.Data["address"])
+}
+
+func TestBackend_escrowKeyManagerFailure1(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": "treasury-service",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/treasury-service/config")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"exportable": true,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/treasury-service/escrow")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"pgpKeys":   []string{"bm90IGEga2V5", "bm90IGEga2V5"},
+		"threshold": 2,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+
+	assert.ErrorContains(t, err, "pgpKeys[0]: invalid PGP key")
+}
+
+func TestBackend_escrowKeyManagerFailure2(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": "treasury-service",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/treasury-service/config")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"exportable": true,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	holder, err := openpgp.NewEntity("holder", "", "holder@example.com",
+		&packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var buf bytes.Buffer
+	if err = holder.Serialize(&buf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pgpKey := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	// one holder listed twice would reach the threshold alone
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/treasury-service/escrow")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"pgpKeys":   []string{pgpKey, pgpKey},
+		"threshold": 2,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+
+	assert.EqualError(t, err, "pgpKeys[0] and pgpKeys[1] are the same PGP key")
+}
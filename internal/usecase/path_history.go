@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathHistory(b *Backend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "history/" + framework.GenericNameRegex("address") + "/?",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.listSignedTxs,
+				},
+			},
+			HelpSynopsis: "List the transactions signed for an address.",
+			Fields: map[string]*framework.FieldSchema{
+				"address": {Type: framework.TypeString},
+			},
+		},
+		{
+			Pattern: "history/" + framework.GenericNameRegex("address") + "/" + framework.GenericNameRegex("sigHash"),
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.readSignedTx,
+				},
+			},
+			HelpSynopsis: "Read a transaction signed for an address by its signing hash.",
+			Fields: map[string]*framework.FieldSchema{
+				"address": {Type: framework.TypeString},
+				"sigHash": {Type: framework.TypeString},
+			},
+		},
+	}
+}
+
+func (b *Backend) listSignedTxs(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	address, ok := data.Get("address").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	hashes, err := req.Storage.List(ctx, fmt.Sprintf("history/%s/", strings.ToLower(address)))
+	if err != nil {
+		b.Logger().Error("Failed to retrieve the list of signed transactions", "address", address, "error", err)
+		return nil, err
+	}
+
+	keyInfo := make(map[string]interface{}, len(hashes))
+	for _, hash := range hashes {
+		record, err := b.retrieveSignedTx(ctx, req, address, hash)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			continue
+		}
+		keyInfo[hash] = map[string]interface{}{
+			"nonce":     record.Nonce,
+			"chain_id":  record.ChainID,
+			"tx_hash":   record.TxHash,
+			"signed_at": record.SignedAt,
+		}
+	}
+
+	return logical.ListResponseWithInfo(hashes, keyInfo), nil
+}
+
+func (b *Backend) readSignedTx(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	address, ok := data.Get("address").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	hash, ok := data.Get("sigHash").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	record, err := b.retrieveSignedTx(ctx, req, address, hash)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, fmt.Errorf("signed transaction does not exist")
+	}
+
+	resp := &logical.Response{
+		Data: record.responseData(),
+	}
+	resp.Data["sigHash"] = record.SigHash
+	resp.Data["nonce"] = record.Nonce
+	resp.Data["chainId"] = record.ChainID
+	resp.Data["signedAt"] = record.SignedAt
+	return resp, nil
+}
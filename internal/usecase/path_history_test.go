@@ -0,0 +1,134 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackend_history(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const (
+		keeperSvc  = "keeper-service"
+		address    = "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704"
+		privateKey = "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1"
+	)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": keeperSvc,
+		"privateKey":  privateKey,
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	signTx := func(nonce string, idempotencyKey string) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+keeperSvc+"/txn/sign")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"address":  address,
+			"data":     "0x",
+			"to":       "0xf809410b0d6f047c603deb311979cd413e025a84",
+			"value":    "1000",
+			"gas":      21000,
+			"nonce":    nonce,
+			"gasPrice": 1,
+			"chainId":  "1",
+		}
+		if idempotencyKey != "" {
+			req.Data["idempotencyKey"] = idempotencyKey
+		}
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	// signing the identical transaction again returns the stored result
+	first, err := signTx("0x1", "")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Nil(t, first.Data["replayed"])
+
+	second, err := signTx("0x1", "")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, first.Data["txHash"], second.Data["txHash"])
+	assert.Equal(t, first.Data["signedTx"], second.Data["signedTx"])
+	assert.Equal(t, true, second.Data["replayed"])
+
+	// an idempotency key replays the stored response and can't be reused for another request
+	keyed, err := signTx("0x2", "payout-42")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Nil(t, keyed.Data["replayed"])
+
+	retried, err := signTx("0x2", "payout-42")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, keyed.Data["txHash"], retried.Data["txHash"])
+	assert.Equal(t, true, retried.Data["replayed"])
+
+	_, err = signTx("0x3", "payout-42")
+	assert.EqualError(t, err, "idempotencyKey payout-42 was already used for a different request")
+
+	_, err = signTx("0x3", "not a valid key")
+	assert.EqualError(t, err, "invalid idempotencyKey, must be 1 to 128 letters, digits, '_', '-' or '.'")
+
+	sign := func(idempotencyKey string) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+keeperSvc+"/sign")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"hash":           "0xaf41db230000000000000000000000000000000000000000000000000000000000000023",
+			"address":        address,
+			"idempotencyKey": idempotencyKey,
+		}
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	signed, err := sign("hash-1")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	resigned, err := sign("hash-1")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, signed.Data["signature"], resigned.Data["signature"])
+	assert.Equal(t, true, resigned.Data["replayed"])
+
+	// the history of the address lists both signed transactions
+	req = logical.TestRequest(t, logical.ListOperation, "history/"+address)
+	req.Storage = storage
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	hashes := resp.Data["keys"].([]string)
+	assert.Len(t, hashes, 2)
+
+	req = logical.TestRequest(t, logical.ReadOperation, "history/"+address+"/"+hashes[0])
+	req.Storage = storage
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, hashes[0], resp.Data["sigHash"])
+	assert.Equal(t, "1", resp.Data["chainId"])
+	assert.Contains(t, []interface{}{first.Data["txHash"], keyed.Data["txHash"]}, resp.Data["txHash"])
+}
+
+func TestBackend_readSignedTxFailure1(t *testing.T) {
+	b, _ := newTestBackend(t)
+	req := logical.TestRequest(t, logical.ReadOperation, "history/0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704/0x01")
+	_, err := b.HandleRequest(context.Background(), req)
+
+	assert.EqualError(t, err, "signed transaction does not exist")
+}
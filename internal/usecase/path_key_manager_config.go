@@ -0,0 +1,285 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathKeyManagerConfig(b *Backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "key-managers/" + framework.GenericNameRegex("name") + "/config",
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.readKeyManagerConfig,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.updateKeyManagerConfig,
+			},
+		},
+		HelpSynopsis: "Read or update the signing settings of a key-manager.",
+		HelpDescription: `
+
+    GET - return the settings of the key-manager
+    POST - update the settings of the key-manager
+
+    `,
+		Fields: map[string]*framework.FieldSchema{
+			"name": {Type: framework.TypeString},
+			"allowedChainIds": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "(optional) Chain IDs the key-manager may sign transactions for. Narrows the mount allowlist: a chain ID has to be allowed by both.",
+			},
+			"allowUnprotected": {
+				Type:        framework.TypeBool,
+				Description: "(optional, default: false) Allow the key-manager to sign pre-EIP155 transactions without a chain ID. The mount has to allow them as well.",
+			},
+			"purposes": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "(optional) Restrict the keys to signing 'transaction', 'typed_data' and/or 'hash' (blind hash signing). Empty allows every purpose.",
+			},
+			"policies": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "(optional) Names of the signing policies that must all allow a request before it is signed.",
+			},
+			"approvers": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "(optional) Vault entity IDs that may approve transactions a policy sends for approval.",
+			},
+			"requiredApprovals": {
+				Type:        framework.TypeInt,
+				Description: "(optional) Number of distinct approvers that must approve a transaction before it is signed.",
+			},
+			"approvalTtl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "(optional, default: 24h) How long a transaction waits for approvals before it expires.",
+			},
+			"signingDelay": {
+				Type:        framework.TypeDurationSecond,
+				Description: "(optional) How long transactions a policy time-locks wait before they can be signed.",
+			},
+			"exportable": {
+				Type:        framework.TypeBool,
+				Description: "(optional, default: false) Allow exporting the private keys as keystore JSON. Cannot be disabled once enabled.",
+			},
+			"labels": {
+				Type:        framework.TypeKVPairs,
+				Description: "(optional) key=value labels of the key-manager to filter the key-manager listing by.",
+			},
+			"boundEntities": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "(optional) IDs or names of the Vault entities that may sign with the key-manager.",
+			},
+			"boundGroups": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "(optional) IDs or names of the Vault groups whose members may sign with the key-manager.",
+			},
+			"boundMetadata": {
+				Type:        framework.TypeKVPairs,
+				Description: "(optional) key=value metadata an entity, or one of its aliases, must carry to sign with the key-manager.",
+			},
+			"rateLimit": {
+				Type:        framework.TypeFloat,
+				Description: "(optional) Signing requests per second allowed across the key-manager. 0 disables the limit.",
+			},
+			"rateLimitBurst": {
+				Type:        framework.TypeInt,
+				Description: "(optional, default: rateLimit rounded up) Signing requests allowed at once across the key-manager.",
+			},
+			"addressRateLimit": {
+				Type:        framework.TypeFloat,
+				Description: "(optional) Signing requests per second allowed per address of the key-manager. 0 disables the limit.",
+			},
+			"addressRateLimitBurst": {
+				Type:        framework.TypeInt,
+				Description: "(optional, default: addressRateLimit rounded up) Signing requests allowed at once per address.",
+			},
+			"poolSize": {
+				Type:        framework.TypeInt,
+				Description: "(optional) Number of unused keys to keep pre-generated for the claim endpoint. 0 disables the pool.",
+			},
+			"allowRetiredSigning": {
+				Type:        framework.TypeBool,
+				Description: "(optional, default: false) Allow keys retired by a rotation to keep signing, e.g. to sweep their funds.",
+			},
+		},
+	}
+}
+
+func (b *Backend) readKeyManagerConfig(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	serviceName, ok := data.Get("name").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	keyManager, err := b.retrieveKeyManager(ctx, req, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if keyManager == nil {
+		return nil, fmt.Errorf("keyManager does not exist")
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"service_name":          keyManager.ServiceName,
+			"allowed_chain_ids":     keyManager.AllowedChainIDs,
+			"allow_unprotected":     keyManager.AllowUnprotected,
+			"purposes":              keyManager.Purposes,
+			"policies":              keyManager.Policies,
+			"approvers":             keyManager.Approvers,
+			"required_approvals":    keyManager.RequiredApprovals,
+			"approval_ttl":          int64(keyManager.ApprovalTTL.Seconds()),
+			"signing_delay":         int64(keyManager.SigningDelay.Seconds()),
+			"exportable":            keyManager.Exportable,
+			"allow_retired_signing": keyManager.AllowRetiredSigning,
+			"pool_size":             keyManager.PoolSize,
+			"labels":                keyManager.Labels,
+			"bound_entities":        keyManager.BoundEntities,
+			"bound_groups":          keyManager.BoundGroups,
+			"bound_metadata":        keyManager.BoundMetadata,
+			"rate_limit":            keyManager.RateLimit.response(),
+			"address_rate_limit":    keyManager.AddressRateLimit.response(),
+		},
+	}, nil
+}
+
+func (b *Backend) updateKeyManagerConfig(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	serviceName, ok := data.Get("name").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	b.keyManagerLock.Lock()
+	defer b.keyManagerLock.Unlock()
+
+	keyManager, err := b.retrieveKeyManager(ctx, req, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if keyManager == nil {
+		return nil, fmt.Errorf("keyManager does not exist")
+	}
+
+	if raw, ok := data.GetOk("allowedChainIds"); ok {
+		keyManager.AllowedChainIDs, err = parseChainIDs(raw.([]string))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if raw, ok := data.GetOk("allowUnprotected"); ok {
+		keyManager.AllowUnprotected = raw.(bool)
+	}
+
+	if raw, ok := data.GetOk("purposes"); ok {
+		keyManager.Purposes, err = parsePurposes(raw.([]string))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if raw, ok := data.GetOk("policies"); ok {
+		for _, name := range raw.([]string) {
+			policy, err := b.retrievePolicy(ctx, req, name)
+			if err != nil {
+				return nil, err
+			}
+			if policy == nil {
+				return nil, fmt.Errorf("policy %s does not exist", name)
+			}
+		}
+		keyManager.Policies = raw.([]string)
+	}
+
+	if raw, ok := data.GetOk("approvers"); ok {
+		keyManager.Approvers = raw.([]string)
+	}
+
+	if raw, ok := data.GetOk("requiredApprovals"); ok {
+		keyManager.RequiredApprovals = raw.(int)
+	}
+
+	if raw, ok := data.GetOk("approvalTtl"); ok {
+		keyManager.ApprovalTTL = time.Duration(raw.(int)) * time.Second
+	}
+
+	if raw, ok := data.GetOk("signingDelay"); ok {
+		keyManager.SigningDelay = time.Duration(raw.(int)) * time.Second
+	}
+
+	if raw, ok := data.GetOk("exportable"); ok {
+		// like transit, a key that may have left Vault can't be trusted to be non-exportable again
+		if keyManager.Exportable && !raw.(bool) {
+			return nil, fmt.Errorf("exportable cannot be disabled once enabled")
+		}
+		keyManager.Exportable = raw.(bool)
+	}
+
+	if raw, ok := data.GetOk("allowRetiredSigning"); ok {
+		keyManager.AllowRetiredSigning = raw.(bool)
+	}
+
+	if raw, ok := data.GetOk("labels"); ok {
+		keyManager.Labels = raw.(map[string]string)
+	}
+
+	if raw, ok := data.GetOk("boundEntities"); ok {
+		keyManager.BoundEntities = raw.([]string)
+	}
+
+	if raw, ok := data.GetOk("boundGroups"); ok {
+		keyManager.BoundGroups = raw.([]string)
+	}
+
+	if raw, ok := data.GetOk("boundMetadata"); ok {
+		keyManager.BoundMetadata = raw.(map[string]string)
+	}
+
+	if keyManager.RateLimit, err = parseRateLimit(data, keyManager.RateLimit, "rateLimit", "rateLimitBurst"); err != nil {
+		return nil, err
+	}
+
+	keyManager.AddressRateLimit, err = parseRateLimit(data, keyManager.AddressRateLimit,
+		"addressRateLimit", "addressRateLimitBurst")
+	if err != nil {
+		return nil, err
+	}
+
+	if raw, ok := data.GetOk("poolSize"); ok {
+		if raw.(int) < 0 || raw.(int) > maxKeyBatch {
+			return nil, fmt.Errorf("poolSize must be between 0 and %d", maxKeyBatch)
+		}
+		keyManager.PoolSize = raw.(int)
+	}
+
+	if keyManager.RequiredApprovals < 0 || keyManager.RequiredApprovals > len(keyManager.Approvers) {
+		return nil, fmt.Errorf("requiredApprovals must be between 0 and the number of approvers (%d)",
+			len(keyManager.Approvers))
+	}
+
+	if _, err = keyManager.fillPool(); err != nil {
+		return nil, err
+	}
+
+	if err = b.saveKeyManager(ctx, req, keyManager); err != nil {
+		return nil, err
+	}
+
+	if err = savePoolIndex(ctx, req.Storage, keyManager); err != nil {
+		return nil, err
+	}
+
+	return b.readKeyManagerConfig(ctx, req, data)
+}
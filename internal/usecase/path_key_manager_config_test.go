@@ -0,0 +1,431 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackend_keyManagerPurposes(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const (
+		treasurySvc = "treasury-service"
+		oracleSvc   = "oracle-service"
+		address     = "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704"
+		privateKey  = "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1"
+	)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	for _, svc := range []string{treasurySvc, oracleSvc} {
+		req = logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"serviceName": svc,
+			"privateKey":  privateKey,
+		}
+		_, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	purposes := map[string][]string{
+		treasurySvc: {purposeTransaction, purposeTypedData},
+		oracleSvc:   {purposeHash},
+	}
+	for svc, p := range purposes {
+		req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+svc+"/config")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"purposes": p,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		assert.Equal(t, p, resp.Data["purposes"])
+	}
+
+	signHash := func(svc string) error {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+svc+"/sign")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"hash":    crypto.Keccak256Hash([]byte("data")).Hex(),
+			"address": address,
+		}
+		_, err := b.HandleRequest(context.Background(), req)
+		return err
+	}
+
+	signTx := func(svc string) error {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+svc+"/txn/sign")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"address":  address,
+			"data":     "0x",
+			"to":       "0xf809410b0d6f047c603deb311979cd413e025a84",
+			"gas":      21000,
+			"nonce":    "0x1",
+			"gasPrice": 1,
+			"chainId":  "1",
+		}
+		_, err := b.HandleRequest(context.Background(), req)
+		return err
+	}
+
+	signTypedData := func(svc string) error {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+svc+"/typed-data/sign")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"address":   address,
+			"typedData": testTypedData,
+		}
+		_, err := b.HandleRequest(context.Background(), req)
+		return err
+	}
+
+	assert.ErrorContains(t, signHash(treasurySvc), `not allowed to sign for purpose "hash"`)
+	assert.NoError(t, signTx(treasurySvc))
+	assert.NoError(t, signTypedData(treasurySvc))
+
+	assert.NoError(t, signHash(oracleSvc))
+	assert.ErrorContains(t, signTx(oracleSvc), `not allowed to sign for purpose "transaction"`)
+	assert.ErrorContains(t, signTypedData(oracleSvc), `not allowed to sign for purpose "typed_data"`)
+}
+
+func TestBackend_keyManagerConfigFailure1(t *testing.T) {
+	b, _ := newTestBackend(t)
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/my-service/config")
+	sm := NewStorageMock(0, 1, 0, 0)
+	req.Storage = sm
+	resp, err := b.HandleRequest(context.Background(), req)
+
+	assert.Nil(t, resp)
+	assert.Equal(t, "keyManager does not exist", err.Error())
+}
+
+func TestBackend_keyManagerConfigFailure2(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": "my-service",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/my-service/config")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"purposes": "blind",
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+
+	assert.ErrorContains(t, err, `invalid purpose "blind"`)
+}
+
+func assertForbidden(t *testing.T, err error) {
+	t.Helper()
+	var coded logical.HTTPCodedError
+	if assert.ErrorAs(t, err, &coded) {
+		assert.Equal(t, http.StatusForbidden, coded.Code())
+	}
+}
+
+func TestBackend_keyManagerIdentityBinding(t *testing.T) {
+	system := &logical.StaticSystemView{
+		EntityVal: &logical.Entity{
+			ID:   "entity-payouts",
+			Name: "payouts",
+			Aliases: []*logical.Alias{
+				{MountType: "kubernetes", Metadata: map[string]string{"service_account_namespace": "payouts"}},
+			},
+		},
+		GroupsVal: []*logical.Group{
+			{ID: "group-finance", Name: "finance"},
+		},
+	}
+	b, err := Factory(context.Background(), &logical.BackendConfig{
+		System:      system,
+		StorageView: &logical.InmemStorage{},
+		BackendUUID: "test",
+	})
+	if err != nil {
+		t.Fatalf("unable to create backend: %v", err)
+	}
+
+	const (
+		payoutsSvc = "payouts"
+		address    = "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704"
+	)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": payoutsSvc,
+		"privateKey":  "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1",
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	bind := func(data map[string]interface{}) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+payoutsSvc+"/config")
+		req.Storage = storage
+		req.Data = data
+		if _, err := b.HandleRequest(context.Background(), req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	sign := func(entityID string) error {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+payoutsSvc+"/sign")
+		req.Storage = storage
+		req.EntityID = entityID
+		req.Data = map[string]interface{}{
+			"hash":    "0xaf41db230000000000000000000000000000000000000000000000000000000000000023",
+			"address": address,
+		}
+		_, err := b.HandleRequest(context.Background(), req)
+		return err
+	}
+
+	bind(map[string]interface{}{"boundEntities": "treasury"})
+	assert.EqualError(t, sign("entity-payouts"),
+		`permission denied: entity "entity-payouts" is not bound to keyManager payouts`)
+	assertForbidden(t, sign(""))
+
+	// entities match by name or ID
+	bind(map[string]interface{}{"boundEntities": "treasury,payouts"})
+	assert.NoError(t, sign("entity-payouts"))
+
+	bind(map[string]interface{}{"boundEntities": "", "boundGroups": "finance"})
+	assert.NoError(t, sign("entity-payouts"))
+
+	bind(map[string]interface{}{"boundGroups": "", "boundMetadata": []string{"service_account_namespace=payouts"}})
+	assert.NoError(t, sign("entity-payouts"))
+
+	bind(map[string]interface{}{"boundMetadata": []string{"service_account_namespace=treasury"}})
+	assertForbidden(t, sign("entity-payouts"))
+
+	// rejected entities do not take tokens from the rate limit of the key-manager
+	bind(map[string]interface{}{"boundMetadata": []string{}, "boundEntities": "treasury", "rateLimit": 0.001, "rateLimitBurst": 1})
+	for i := 0; i < 3; i++ {
+		assertForbidden(t, sign("entity-payouts"))
+	}
+	bind(map[string]interface{}{"boundEntities": "payouts"})
+	assert.NoError(t, sign("entity-payouts"))
+}
+
+func TestBackend_keyManagerIdentityBindingKeyMaterial(t *testing.T) {
+	b, err := Factory(context.Background(), &logical.BackendConfig{
+		System: &logical.StaticSystemView{
+			EntityVal: &logical.Entity{ID: "entity-payouts", Name: "payouts"},
+		},
+		StorageView: &logical.InmemStorage{},
+		BackendUUID: "test",
+	})
+	if err != nil {
+		t.Fatalf("unable to create backend: %v", err)
+	}
+	storage := &logical.InmemStorage{}
+
+	const (
+		payoutsSvc = "payouts"
+		mnemonic   = "test test test test test test test test test test test junk"
+		address    = "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+	)
+
+	request := func(operation logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		req := logical.TestRequest(t, operation, path)
+		req.Storage = storage
+		req.EntityID = "entity-payouts"
+		req.Data = data
+		return b.HandleRequest(context.Background(), req)
+	}
+	update := func(path string, data map[string]interface{}) *logical.Response {
+		resp, err := request(logical.UpdateOperation, path, data)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return resp
+	}
+
+	update("key-managers", map[string]interface{}{
+		"serviceName": payoutsSvc,
+		"hd":          true,
+		"mnemonic":    mnemonic,
+	})
+	update("policies/payouts", map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{"name": "delayed", "expression": `true`, "effect": "delay"},
+		},
+	})
+	update("key-managers/"+payoutsSvc+"/config", map[string]interface{}{
+		"exportable":    true,
+		"policies":      "payouts",
+		"signingDelay":  1,
+		"boundEntities": "payouts",
+	})
+
+	// queued while the entity is still bound
+	resp := update("key-managers/"+payoutsSvc+"/txn/sign", map[string]interface{}{
+		"address":  address,
+		"data":     "0x",
+		"to":       "0xaa1fd73c4981aeb9d051eff70b055eb50ba94c32",
+		"value":    "1000",
+		"gas":      21000,
+		"nonce":    "0x0",
+		"gasPrice": 1,
+		"chainId":  "1",
+	})
+	timelockID := resp.Data["timelockId"].(string)
+
+	update("key-managers/"+payoutsSvc+"/config", map[string]interface{}{"boundEntities": "treasury"})
+
+	for path, data := range map[string]map[string]interface{}{
+		"key-managers/" + payoutsSvc + "/keystore/export": {"address": address, "passphrase": "passphrase"},
+		"key-managers/" + payoutsSvc + "/escrow":          {"pgpKeys": []string{}, "threshold": 2},
+		"key-managers/" + payoutsSvc + "/claim":           {},
+		"key-managers/" + payoutsSvc + "/derive":          {"derivationId": "user-1"},
+		"backup/" + payoutsSvc:                            {},
+	} {
+		_, err := request(logical.UpdateOperation, path, data)
+		assertForbidden(t, err)
+	}
+	_, err = request(logical.ReadOperation, "key-managers/"+payoutsSvc+"/session", nil)
+	assertForbidden(t, err)
+
+	time.Sleep(1100 * time.Millisecond)
+	_, err = request(logical.UpdateOperation, "timelocks/"+timelockID+"/release", nil)
+	assertForbidden(t, err)
+}
+
+func TestBackend_keyManagerRateLimits(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const (
+		botSvc  = "trading-bot"
+		address = "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704"
+	)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": botSvc,
+		"privateKey":  "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"serviceName": botSvc,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	otherAddress := resp.Data["address"].(string)
+
+	update := func(path string, data map[string]interface{}) *logical.Response {
+		req := logical.TestRequest(t, logical.UpdateOperation, path)
+		req.Storage = storage
+		req.Data = data
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return resp
+	}
+
+	sign := func(entityID, address string) error {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+botSvc+"/sign")
+		req.Storage = storage
+		req.EntityID = entityID
+		req.Data = map[string]interface{}{
+			"hash":    "0xaf41db230000000000000000000000000000000000000000000000000000000000000023",
+			"address": address,
+		}
+		_, err := b.HandleRequest(context.Background(), req)
+		return err
+	}
+
+	// rates low enough that no token comes back during the test
+	resp = update("key-managers/"+botSvc+"/config", map[string]interface{}{
+		"rateLimit":        0.001,
+		"rateLimitBurst":   3,
+		"addressRateLimit": 0.001,
+	})
+	assert.Equal(t, map[string]interface{}{"rate": 0.001, "burst": 1}, resp.Data["address_rate_limit"])
+
+	assert.NoError(t, sign("", address))
+	err = sign("", strings.ToLower(address))
+	var coded logical.HTTPCodedError
+	if assert.ErrorAs(t, err, &coded) {
+		assert.Equal(t, http.StatusTooManyRequests, coded.Code())
+	}
+	assert.EqualError(t, err, "rate limit quota exceeded: signing rate of address "+strings.ToLower(address)+" exceeded")
+
+	assert.NoError(t, sign("", otherAddress))
+	assert.EqualError(t, sign("", otherAddress), "rate limit quota exceeded: signing rate of keyManager "+botSvc+" exceeded")
+
+	// lifting the limits takes effect right away
+	update("key-managers/"+botSvc+"/config", map[string]interface{}{
+		"rateLimit":        0,
+		"addressRateLimit": 0,
+	})
+	assert.NoError(t, sign("", otherAddress))
+
+	update("config", map[string]interface{}{
+		"entityRateLimit":      0.001,
+		"entityRateLimitBurst": 1,
+	})
+	assert.NoError(t, sign("runaway-service", address))
+	assert.EqualError(t, sign("runaway-service", address),
+		`rate limit quota exceeded: signing rate of entity "runaway-service" exceeded`)
+	assert.NoError(t, sign("other-service", address))
+
+	listAudit := func() []string {
+		req := logical.TestRequest(t, logical.ListOperation, "audit")
+		req.Storage = storage
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return resp.Data["keys"].([]string)
+	}
+
+	// the rate-limited rejections of a key-manager and entity share one record per window
+	assert.Len(t, listAudit(), 7)
+
+	// the rejections coalesced into a window are recorded once it is over
+	(b.(*Backend)).flushRateLimitedAudits(context.Background(), storage, time.Now().Add(auditCoalesceWindow))
+	ids := listAudit()
+	if assert.Len(t, ids, 8) {
+		req = logical.TestRequest(t, logical.ReadOperation, "audit/"+ids[len(ids)-1])
+		req.Storage = storage
+		resp, err = b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		assert.Equal(t, auditDecisionRejected, resp.Data["decision"])
+		assert.Contains(t, resp.Data["reason"],
+			"signing rate of keyManager "+botSvc+" exceeded (1 more rate-limited requests since")
+	}
+}
@@ -0,0 +1,246 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const operationExportKeystore = "exportKeystore"
+
+var (
+	// scrypt parameters of exported keystores, the geth defaults
+	keystoreScryptN = keystore.StandardScryptN
+	keystoreScryptP = keystore.StandardScryptP
+)
+
+// Upper bounds of the KDF parameters of imported keystores. The KDF runs before the passphrase can
+// be checked, so a crafted keystore could otherwise hold a request for minutes and gigabytes of memory.
+// The scrypt work n*r*p is bounded by that of geth's standard keystores, which also caps its memory.
+const (
+	maxKeystoreScryptN    = keystore.StandardScryptN
+	maxKeystoreScryptWork = keystore.StandardScryptN * 8 * keystore.StandardScryptP
+	maxKeystorePBKDF2C    = 1 << 18
+	maxKeystoreKeyBytes   = 32
+)
+
+func pathKeystore(b *Backend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "key-managers/" + framework.GenericNameRegex("name") + "/keystore/import",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.importKeystore,
+				},
+			},
+			HelpSynopsis: "Import a private key from a Web3 Secret Storage (keystore v3) JSON file.",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {Type: framework.TypeString},
+				"keystore": {
+					Type:        framework.TypeString,
+					Description: "The keystore v3 JSON, as written by geth.",
+				},
+				"passphrase": {
+					Type:        framework.TypeString,
+					Description: "The passphrase the keystore is encrypted with.",
+				},
+			},
+		},
+		{
+			Pattern: "key-managers/" + framework.GenericNameRegex("name") + "/keystore/export",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.exportKeystore,
+				},
+			},
+			HelpSynopsis: "Export a private key of an exportable key-manager as a keystore v3 JSON.",
+			HelpDescription: `
+
+    POST - return the private key of the address as a keystore v3 JSON encrypted with the passphrase.
+    The key-manager has to be configured as exportable, and its signing policies have to allow the
+    exportKeystore operation.
+
+    `,
+			Fields: map[string]*framework.FieldSchema{
+				"name": {Type: framework.TypeString},
+				"address": {
+					Type:        framework.TypeString,
+					Description: "The address whose private key is exported.",
+				},
+				"passphrase": {
+					Type:        framework.TypeString,
+					Description: "The passphrase to encrypt the keystore with.",
+				},
+			},
+		},
+	}
+}
+
+func (b *Backend) importKeystore(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	serviceName, ok := data.Get("name").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	keyJSON, ok := data.Get("keystore").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	passphrase, ok := data.Get("passphrase").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	if err := checkKeystoreKDF([]byte(keyJSON)); err != nil {
+		return nil, err
+	}
+
+	key, err := keystore.DecryptKey([]byte(keyJSON), passphrase)
+	if err != nil {
+		b.Logger().Error("Failed to decrypt the keystore", "service_name", serviceName,
+			"keystore", redactedValue, "error", err)
+		return nil, fmt.Errorf("failed to decrypt the keystore: %w", err)
+	}
+	defer zeroKey(key.PrivateKey)
+
+	return b.importKeyPair(ctx, req, serviceName, key.PrivateKey)
+}
+
+// checkKeystoreKDF rejects keystores whose KDF would cost more than the keystores geth writes.
+// The parameters are read as floats, the way keystore.DecryptKey reads them.
+func checkKeystoreKDF(keyJSON []byte) error {
+	var ks struct {
+		Crypto struct {
+			KDF       string `json:"kdf"`
+			KDFParams struct {
+				N     float64 `json:"n"`
+				R     float64 `json:"r"`
+				P     float64 `json:"p"`
+				C     float64 `json:"c"`
+				DKLen float64 `json:"dklen"`
+			} `json:"kdfparams"`
+		} `json:"crypto"`
+	}
+	if err := json.Unmarshal(keyJSON, &ks); err != nil {
+		return fmt.Errorf("failed to decrypt the keystore: %w", err)
+	}
+
+	params := ks.Crypto.KDFParams
+	if params.DKLen > maxKeystoreKeyBytes {
+		return fmt.Errorf("keystore dklen %.0f exceeds %d", params.DKLen, maxKeystoreKeyBytes)
+	}
+	switch ks.Crypto.KDF {
+	case "scrypt":
+		if params.N > maxKeystoreScryptN {
+			return fmt.Errorf("keystore scrypt n %.0f exceeds %d", params.N, maxKeystoreScryptN)
+		}
+		if params.N*params.R*params.P > maxKeystoreScryptWork {
+			return fmt.Errorf("keystore scrypt parameters n=%.0f r=%.0f p=%.0f exceed a work of n*r*p=%d",
+				params.N, params.R, params.P, maxKeystoreScryptWork)
+		}
+	case "pbkdf2":
+		if params.C > maxKeystorePBKDF2C {
+			return fmt.Errorf("keystore pbkdf2 iterations %.0f exceed %d", params.C, maxKeystorePBKDF2C)
+		}
+	}
+	return nil
+}
+
+func (b *Backend) exportKeystore(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	serviceName, ok := data.Get("name").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	address, ok := data.Get("address").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	passphrase, ok := data.Get("passphrase").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	if passphrase == "" {
+		return nil, errors.New("passphrase is required to encrypt the keystore")
+	}
+
+	keyManager, err := b.retrieveKeyManager(ctx, req, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if keyManager == nil {
+		return nil, fmt.Errorf("keyManager does not exist")
+	}
+
+	if err = b.checkIdentity(keyManager, req.EntityID); err != nil {
+		return nil, err
+	}
+
+	if !keyManager.Exportable {
+		return nil, fmt.Errorf("keyManager %s is not exportable", serviceName)
+	}
+
+	keyPair := keyManager.keyPair(address)
+	if keyPair == nil {
+		return nil, errors.New("no private key for the input address")
+	}
+
+	if _, err = b.enforcePolicies(ctx, req, keyManager, &policyInput{
+		operation:   operationExportKeystore,
+		serviceName: serviceName,
+		address:     address,
+		entityID:    req.EntityID,
+	}); err != nil {
+		return nil, err
+	}
+
+	privateKey, err := keyManager.privateKey(keyPair)
+	if err != nil {
+		b.Logger().Error("Error reconstructing private key from retrieved hex", "error", err)
+		return nil, fmt.Errorf("error reconstructing private key from retrieved hex")
+	}
+	defer zeroKey(privateKey)
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	keyJSON, err := keystore.EncryptKey(&keystore.Key{
+		Id:         id,
+		Address:    common.HexToAddress(keyPair.Address),
+		PrivateKey: privateKey,
+	}, passphrase, keystoreScryptN, keystoreScryptP)
+	if err != nil {
+		b.Logger().Error("Failed to encrypt the keystore", "service_name", serviceName, "error", err)
+		return nil, err
+	}
+
+	b.Logger().Warn("Private key exported as keystore", "service_name", serviceName, "address", keyPair.Address,
+		"entity_id", req.EntityID)
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"address":  keyPair.Address,
+			"keystore": string(keyJSON),
+		},
+	}, nil
+}
@@ -0,0 +1,145 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackend_keystore(t *testing.T) {
+	defer func(n, p int) { keystoreScryptN, keystoreScryptP = n, p }(keystoreScryptN, keystoreScryptP)
+	keystoreScryptN, keystoreScryptP = keystore.LightScryptN, keystore.LightScryptP
+
+	b, _ := newTestBackend(t)
+
+	const (
+		migratedSvc = "migrated-service"
+		address     = "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704"
+	)
+
+	privateKey, err := crypto.HexToECDSA("3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	gethKeystore, err := keystore.EncryptKey(&keystore.Key{
+		Id:         uuid.New(),
+		Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+		PrivateKey: privateKey,
+	}, "geth-node", keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+migratedSvc+"/keystore/import")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"keystore":   string(gethKeystore),
+		"passphrase": "wrong",
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	assert.EqualError(t, err, "failed to decrypt the keystore: could not decrypt key with given password")
+
+	req.Data["passphrase"] = "geth-node"
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, address, resp.Data["address"])
+
+	_, err = b.HandleRequest(context.Background(), req)
+	assert.EqualError(t, err, "address "+address+" already belongs to keyManager "+migratedSvc)
+
+	export := func() (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+migratedSvc+"/keystore/export")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"address":    address,
+			"passphrase": "offsite",
+		}
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	_, err = export()
+	assert.EqualError(t, err, "keyManager "+migratedSvc+" is not exportable")
+
+	configure := func(data map[string]interface{}) error {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+migratedSvc+"/config")
+		req.Storage = storage
+		req.Data = data
+		_, err := b.HandleRequest(context.Background(), req)
+		return err
+	}
+
+	if err = configure(map[string]interface{}{"exportable": true}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.EqualError(t, configure(map[string]interface{}{"exportable": false}),
+		"exportable cannot be disabled once enabled")
+
+	resp, err = export()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	key, err := keystore.DecryptKey([]byte(resp.Data["keystore"].(string)), "offsite")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, address, key.Address.Hex())
+	assert.Equal(t, crypto.FromECDSA(privateKey), crypto.FromECDSA(key.PrivateKey))
+
+	// signing policies gate the export
+	req = logical.TestRequest(t, logical.UpdateOperation, "policies/signing-only")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{
+				"expression": `request.operation == "signTx"`,
+				"effect":     "allow",
+			},
+		},
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err = configure(map[string]interface{}{"policies": "signing-only"}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	_, err = export()
+	assert.ErrorContains(t, err, "request denied by policy signing-only")
+}
+
+func TestBackend_importKeystoreFailure1(t *testing.T) {
+	b, _ := newTestBackend(t)
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/migrated-service/keystore/import")
+	req.Data = map[string]interface{}{
+		"keystore":   "{}",
+		"passphrase": "geth-node",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+
+	assert.ErrorContains(t, err, "failed to decrypt the keystore")
+}
+
+func TestBackend_importKeystoreFailure2(t *testing.T) {
+	b, _ := newTestBackend(t)
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/migrated-service/keystore/import")
+	req.Data = map[string]interface{}{
+		"keystore":   `{"version":3,"crypto":{"kdf":"scrypt","kdfparams":{"n":1073741824,"r":8,"p":1,"dklen":32,"salt":"00"}}}`,
+		"passphrase": "geth-node",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+
+	assert.EqualError(t, err, "keystore scrypt n 1073741824 exceeds 262144")
+
+	req.Data["keystore"] = `{"version":3,"crypto":{"kdf":"scrypt","kdfparams":{"n":262144,"r":8,"p":64,"dklen":32,"salt":"00"}}}`
+	_, err = b.HandleRequest(context.Background(), req)
+
+	assert.EqualError(t, err, "keystore scrypt parameters n=262144 r=8 p=64 exceed a work of n*r*p=2097152")
+}
@@ -0,0 +1,291 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathPolicies(b *Backend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "policies/?",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.listPolicies,
+				},
+			},
+			HelpSynopsis: "List all the signing policies.",
+		},
+		{
+			Pattern: "policies/" + framework.GenericNameRegex("name"),
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.readPolicy,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.writePolicy,
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.deletePolicy,
+				},
+			},
+			HelpSynopsis: "Create, get or delete a signing policy by name",
+			HelpDescription: `
+
+    GET - return the policy by the name
+    POST - create or replace the policy
+    DELETE - deletes the policy by the name
+
+    A policy is an ordered list of rules, each a CEL expression with an effect of "allow", "deny",
+    "require_approval" or "delay". The first matching rule decides, and a request that matches no
+    rule is denied. "require_approval" and "delay" queue txn/sign requests for approval or until the
+    signing delay of the key-manager has passed. Expressions can use the variables request, tx,
+    typedData, hash and identity.
+
+    `,
+			Fields: map[string]*framework.FieldSchema{
+				"name": {Type: framework.TypeString},
+				"rules": {
+					Type:        framework.TypeSlice,
+					Description: "Ordered list of rules with name, expression and effect.",
+				},
+			},
+		},
+		{
+			Pattern: "policies/" + framework.GenericNameRegex("name") + "/evaluate",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.evaluatePolicyDryRun,
+				},
+			},
+			HelpSynopsis: "Evaluate a policy against a sample signing request without signing anything.",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {Type: framework.TypeString},
+				"operation": {
+					Type:        framework.TypeString,
+					Description: "The signing operation: sign, signTx or signTypedData.",
+					Default:     operationSignTx,
+				},
+				"serviceName": {
+					Type:        framework.TypeString,
+					Description: "(optional) The key-manager of the sample request.",
+				},
+				"address": {
+					Type:        framework.TypeString,
+					Description: "(optional) The signing address of the sample request.",
+				},
+				"tx": {
+					Type:        framework.TypeMap,
+					Description: "(optional) The transaction fields, as accepted by txn/sign.",
+				},
+				"hash": {
+					Type:        framework.TypeString,
+					Description: "(optional) The hash of a sign request.",
+				},
+				"typedData": {
+					Type:        framework.TypeMap,
+					Description: "(optional) The EIP-712 typed data of a typed-data/sign request.",
+				},
+				"time": {
+					Type:        framework.TypeString,
+					Description: "(optional, default: now) RFC3339 time of the sample request.",
+				},
+			},
+		},
+	}
+}
+
+func (b *Backend) retrievePolicy(ctx context.Context, req *logical.Request, name string) (*Policy, error) {
+	path := fmt.Sprintf("policies/%s", name)
+	entry, err := req.Storage.Get(ctx, path)
+	if err != nil {
+		b.Logger().Error("Failed to retrieve the policy", "path", path, "error", err)
+		return nil, err
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	var policy Policy
+	if err = entry.DecodeJSON(&policy); err != nil {
+		b.Logger().Error("Failed to decode policy", "path", path, "error", err)
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (b *Backend) listPolicies(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	vals, err := req.Storage.List(ctx, "policies/")
+	if err != nil {
+		b.Logger().Error("Failed to retrieve the list of policies", "error", err)
+		return nil, err
+	}
+
+	return logical.ListResponse(vals), nil
+}
+
+func (b *Backend) readPolicy(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	name, ok := data.Get("name").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	policy, err := b.retrievePolicy(ctx, req, name)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, fmt.Errorf("policy does not exist")
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"name":  policy.Name,
+			"rules": policy.Rules,
+		},
+	}, nil
+}
+
+func (b *Backend) writePolicy(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	name, ok := data.Get("name").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	rulesInput, ok := data.Get("rules").([]interface{})
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	policy := &Policy{Name: name}
+	raw, err := json.Marshal(rulesInput)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(raw, &policy.Rules); err != nil {
+		return nil, fmt.Errorf("invalid rules: %w", err)
+	}
+
+	if err = b.validatePolicy(policy); err != nil {
+		return nil, err
+	}
+
+	b.forgetPolicy(name)
+	entry, _ := logical.StorageEntryJSON(fmt.Sprintf("policies/%s", name), policy)
+	if err = req.Storage.Put(ctx, entry); err != nil {
+		b.Logger().Error("Failed to save the policy to storage", "name", name, "error", err)
+		return nil, err
+	}
+
+	return b.readPolicy(ctx, req, data)
+}
+
+func (b *Backend) deletePolicy(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	name, ok := data.Get("name").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	if err := req.Storage.Delete(ctx, fmt.Sprintf("policies/%s", name)); err != nil {
+		b.Logger().Error("Failed to delete the policy from storage", "name", name, "error", err)
+		return nil, err
+	}
+	b.forgetPolicy(name)
+	return nil, nil
+}
+
+func (b *Backend) evaluatePolicyDryRun(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	name, ok := data.Get("name").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	policy, err := b.retrievePolicy(ctx, req, name)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, fmt.Errorf("policy does not exist")
+	}
+
+	input := &policyInput{
+		operation:   data.Get("operation").(string),
+		serviceName: data.Get("serviceName").(string),
+		address:     data.Get("address").(string),
+		hash:        data.Get("hash").(string),
+		entityID:    req.EntityID,
+	}
+
+	if rawTime := data.Get("time").(string); rawTime != "" {
+		input.time, err = time.Parse(time.RFC3339, rawTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time: %w", err)
+		}
+	}
+
+	if txInput := data.Get("tx").(map[string]interface{}); len(txInput) > 0 {
+		raw := map[string]interface{}{"name": input.serviceName, "address": input.address}
+		for k, v := range txInput {
+			raw[k] = v
+		}
+		config, err := b.retrieveConfig(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		fields, err := b.validateAndGetTx(&framework.FieldData{Raw: raw, Schema: pathSignTx(b).Fields}, config)
+		if err != nil {
+			return nil, err
+		}
+		input.tx, input.chainID = fields.tx, fields.chainID
+	}
+
+	if typedDataInput := data.Get("typedData").(map[string]interface{}); len(typedDataInput) > 0 {
+		input.typedData, err = decodeTypedData(typedDataInput)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	activation, err := b.policyActivation(input)
+	if err != nil {
+		return nil, err
+	}
+
+	decision := b.evaluatePolicy(policy, activation)
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"allowed":      decision.Allowed(),
+			"effect":       decision.Effect,
+			"matched_rule": decision.MatchedRule,
+			"rules":        decision.Rules,
+			"explanation":  decisionReason(decision),
+		},
+	}, nil
+}
@@ -0,0 +1,311 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+var testPolicyRules = []interface{}{
+	map[string]interface{}{
+		"name":       "cold-wallet",
+		"expression": `tx.to == "0xf809410b0d6f047c603deb311979cd413e025a84"`,
+		"effect":     "allow",
+	},
+	map[string]interface{}{
+		"name":       "weekend",
+		"expression": `request.time.getDayOfWeek() == 0 || request.time.getDayOfWeek() == 6`,
+		"effect":     "deny",
+	},
+	map[string]interface{}{
+		"name":       "small-value",
+		"expression": `request.operation == "signTx" && tx.value < 1e18`,
+		"effect":     "allow",
+	},
+}
+
+func TestBackend_policies(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "policies/treasury")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"rules": testPolicyRules,
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.ListOperation, "policies")
+	req.Storage = storage
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, []string{"treasury"}, resp.Data["keys"])
+
+	evaluate := func(data map[string]interface{}) *logical.Response {
+		req := logical.TestRequest(t, logical.UpdateOperation, "policies/treasury/evaluate")
+		req.Storage = storage
+		req.Data = data
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return resp
+	}
+
+	// a large transfer to the cold wallet is allowed, even on a Sunday
+	resp = evaluate(map[string]interface{}{
+		"time": "2024-01-07T10:00:00Z",
+		"tx": map[string]interface{}{
+			"to":      "0xF809410B0D6F047C603DEB311979CD413E025A84",
+			"value":   "5000000000000000000",
+			"data":    "0x",
+			"nonce":   "1",
+			"chainId": "1",
+		},
+	})
+	assert.Equal(t, true, resp.Data["allowed"])
+	assert.Equal(t, "cold-wallet", resp.Data["matched_rule"])
+
+	// a small transfer elsewhere is allowed on a weekday
+	smallTransfer := map[string]interface{}{
+		"to":      "0xaa1fd73c4981aeb9d051eff70b055eb50ba94c32",
+		"value":   "100000000000000000",
+		"data":    "0x",
+		"nonce":   "1",
+		"chainId": "1",
+	}
+	resp = evaluate(map[string]interface{}{
+		"time": "2024-01-08T10:00:00Z",
+		"tx":   smallTransfer,
+	})
+	assert.Equal(t, true, resp.Data["allowed"])
+	assert.Equal(t, "small-value", resp.Data["matched_rule"])
+
+	// but not on a Saturday
+	resp = evaluate(map[string]interface{}{
+		"time": "2024-01-06T10:00:00Z",
+		"tx":   smallTransfer,
+	})
+	assert.Equal(t, false, resp.Data["allowed"])
+	assert.Equal(t, "weekend", resp.Data["matched_rule"])
+
+	// a large transfer elsewhere matches no rule
+	smallTransfer["value"] = "2000000000000000000"
+	resp = evaluate(map[string]interface{}{
+		"time": "2024-01-08T10:00:00Z",
+		"tx":   smallTransfer,
+	})
+	assert.Equal(t, false, resp.Data["allowed"])
+	assert.Equal(t, "treasury (no rule matched)", resp.Data["explanation"])
+}
+
+func TestBackend_policiesEnforced(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const (
+		keeperSvc  = "keeper-service"
+		address    = "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704"
+		privateKey = "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1"
+	)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": keeperSvc,
+		"privateKey":  privateKey,
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+keeperSvc+"/config")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"policies": "limits",
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	assert.ErrorContains(t, err, "policy limits does not exist")
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "policies/limits")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{
+				"name":       "small-value",
+				"expression": `tx.value < 1e18 && tx.selector == ""`,
+				"effect":     "allow",
+			},
+		},
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+keeperSvc+"/config")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"policies": "limits",
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	signTx := func(value string, nonce string) error {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+keeperSvc+"/txn/sign")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"address":  address,
+			"data":     "0x",
+			"to":       "0xf809410b0d6f047c603deb311979cd413e025a84",
+			"value":    value,
+			"gas":      21000,
+			"nonce":    nonce,
+			"gasPrice": 1,
+			"chainId":  "1",
+		}
+		_, err := b.HandleRequest(context.Background(), req)
+		return err
+	}
+
+	assert.NoError(t, signTx("1000", "0x1"))
+	assert.ErrorContains(t, signTx("1000000000000000000", "0x1"), "request denied by policy limits (no rule matched)")
+
+	// the tx variables are empty for hash signing, so the rule fails closed
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+keeperSvc+"/sign")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"hash":    "0xaf41db230000000000000000000000000000000000000000000000000000000000000023",
+		"address": address,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	assert.ErrorContains(t, err, "request denied by policy limits (rule small-value failed")
+
+	// the compiled rules follow the policy when it is replaced
+	req = logical.TestRequest(t, logical.UpdateOperation, "policies/limits")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{
+				"name":       "small-value",
+				"expression": `tx.value <= 1e18 && tx.selector == ""`,
+				"effect":     "allow",
+			},
+		},
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.NoError(t, signTx("1000000000000000000", "0x2"))
+
+	// and when it is stored without going through the policy path, as a restore does
+	entry, _ := logical.StorageEntryJSON("policies/limits", &Policy{
+		Name:  "limits",
+		Rules: []*PolicyRule{{Name: "small-value", Expression: `tx.value < 1e18`, Effect: "allow"}},
+	})
+	if err = storage.Put(context.Background(), entry); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.ErrorContains(t, signTx("1000000000000000000", "0x3"), "request denied by policy limits (no rule matched)")
+}
+
+func TestBackend_writePolicyFailure1(t *testing.T) {
+	b, _ := newTestBackend(t)
+	req := logical.TestRequest(t, logical.UpdateOperation, "policies/invalid")
+	req.Data = map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{
+				"name":       "not-bool",
+				"expression": `tx.value + 1.0`,
+				"effect":     "allow",
+			},
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+
+	assert.ErrorContains(t, err, `rule "not-bool": expression must evaluate to a bool`)
+}
+
+func TestBackend_writePolicyFailure2(t *testing.T) {
+	b, _ := newTestBackend(t)
+	req := logical.TestRequest(t, logical.UpdateOperation, "policies/invalid")
+	req.Data = map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{
+				"expression": `true`,
+				"effect":     "maybe",
+			},
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+
+	assert.Equal(t, `rule "rule-0": invalid effect "maybe", must be "allow", "deny", "require_approval" or "delay"`, err.Error())
+}
+
+// groupsUnavailableSystemView fails every group lookup, as when the identity store is unreachable.
+type groupsUnavailableSystemView struct {
+	logical.StaticSystemView
+}
+
+func (groupsUnavailableSystemView) GroupsForEntity(string) ([]*logical.Group, error) {
+	return nil, errors.New("identity store unavailable")
+}
+
+func TestBackend_policiesIdentityLookupFailure(t *testing.T) {
+	b, err := Factory(context.Background(), &logical.BackendConfig{
+		System:      groupsUnavailableSystemView{},
+		StorageView: &logical.InmemStorage{},
+		BackendUUID: "test",
+	})
+	if err != nil {
+		t.Fatalf("unable to create backend: %v", err)
+	}
+	storage := &logical.InmemStorage{}
+
+	update := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, path)
+		req.Storage = storage
+		req.EntityID = "entity-payouts"
+		req.Data = data
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	for path, data := range map[string]map[string]interface{}{
+		"key-managers": {
+			"serviceName": "treasury",
+			"privateKey":  "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1",
+		},
+		"policies/not-finance": {
+			"rules": []interface{}{
+				map[string]interface{}{"expression": `!("finance" in identity.groups)`, "effect": "allow"},
+			},
+		},
+	} {
+		if _, err = update(path, data); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if _, err = update("key-managers/treasury/config", map[string]interface{}{"policies": "not-finance"}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// identity rules are not evaluated against an identity without its groups
+	_, err = update("key-managers/treasury/sign", map[string]interface{}{
+		"hash":    "0xaf41db230000000000000000000000000000000000000000000000000000000000000023",
+		"address": "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704",
+	})
+	assert.EqualError(t, err, `failed to look up the groups of entity "entity-payouts": identity store unavailable`)
+
+	_, err = update("policies/not-finance/evaluate", map[string]interface{}{"operation": "sign"})
+	assert.EqualError(t, err, `failed to look up the groups of entity "entity-payouts": identity store unavailable`)
+}
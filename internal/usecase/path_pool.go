@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathPool(b *Backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "key-managers/" + framework.GenericNameRegex("name") + "/claim",
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.claim,
+			},
+		},
+		HelpSynopsis: "Claim a pre-generated key from the pool of the key-manager.",
+		HelpDescription: `
+
+    POST - take an unused key out of the pool of the key-manager, which is configured with poolSize,
+    and return its address. Every key is claimed at most once. An empty pool generates the key on
+    the spot. The pool is refilled by the periodic function of the mount.
+
+    `,
+		Fields: map[string]*framework.FieldSchema{
+			"name": {Type: framework.TypeString},
+		},
+	}
+}
+
+func (b *Backend) claim(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	serviceName, ok := data.Get("name").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	keyPair, err := b.claimKeyPair(ctx, req, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	emitKeyManagerMetric(metricKeyManagerCreated, serviceName)
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"service_name": serviceName,
+			"address":      keyPair.Address,
+			"public_key":   keyPair.PublicKey,
+		},
+	}
+	if keyPair.DerivationPath != "" {
+		resp.Data["derivation_path"] = keyPair.DerivationPath
+	}
+	return resp, nil
+}
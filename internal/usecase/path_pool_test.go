@@ -0,0 +1,214 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackend_createKeyManagerCount(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": "deposits",
+		"count":       5,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	addresses := resp.Data["addresses"].([]string)
+	assert.Len(t, addresses, 5)
+	assert.Equal(t, addresses[0], resp.Data["address"])
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"serviceName": "hd-deposits",
+		"mnemonic":    "test test test test test test test test test test test junk",
+		"count":       2,
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, []string{
+		"0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+		"0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+	}, resp.Data["addresses"])
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"serviceName": "deposits",
+		"count":       1001,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	assert.EqualError(t, err, "count must be between 1 and 1000")
+}
+
+func TestBackend_claim(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const depositsSvc = "deposits"
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": depositsSvc,
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+depositsSvc+"/config")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"poolSize": 3,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	read := func() ([]string, []string) {
+		req := logical.TestRequest(t, logical.ReadOperation, "key-managers/"+depositsSvc)
+		req.Storage = storage
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		var pooled []string
+		for _, address := range resp.Data["addresses"].([]string) {
+			info := resp.Data["key_info"].(map[string]interface{})[address].(map[string]interface{})
+			if info["status"] == "pooled" {
+				pooled = append(pooled, address)
+			}
+		}
+		return resp.Data["addresses"].([]string), pooled
+	}
+
+	addresses, pooled := read()
+	assert.Len(t, addresses, 4)
+	assert.Len(t, pooled, 3)
+	pooledAddress := pooled[0]
+
+	sign := func(address string) error {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+depositsSvc+"/sign")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"hash":    "0xaf41db230000000000000000000000000000000000000000000000000000000000000023",
+			"address": address,
+		}
+		_, err := b.HandleRequest(context.Background(), req)
+		return err
+	}
+
+	assert.EqualError(t, sign(pooledAddress), "address "+pooledAddress+" has not been claimed from the key pool")
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+depositsSvc+"/claim")
+	req.Storage = storage
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, pooledAddress, resp.Data["address"])
+	assert.NoError(t, sign(pooledAddress))
+
+	// the periodic function tops the pool up again
+	req = &logical.Request{
+		Operation: logical.RollbackOperation,
+		Storage:   storage,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	addresses, pooled = read()
+	assert.Len(t, addresses, 5)
+	assert.Len(t, pooled, 3)
+	assert.NotContains(t, pooled, pooledAddress)
+}
+
+func TestBackend_claimConcurrentCreate(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const (
+		depositsSvc = "deposits"
+		requests    = 10
+	)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": depositsSvc,
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+depositsSvc+"/config")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"poolSize": 2,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// keys created while the pool is claimed from and refilled are all kept
+	var wg sync.WaitGroup
+	errs := make(chan error, 3*requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+			req.Storage = storage
+			req.Data = map[string]interface{}{
+				"serviceName": depositsSvc,
+			}
+			_, err := b.HandleRequest(context.Background(), req)
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+depositsSvc+"/claim")
+			req.Storage = storage
+			_, err := b.HandleRequest(context.Background(), req)
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			errs <- b.(*Backend).refillPool(context.Background(), storage, depositsSvc)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "key-managers/"+depositsSvc)
+	req.Storage = storage
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var claimed int
+	for _, info := range resp.Data["key_info"].(map[string]interface{}) {
+		if info.(map[string]interface{})["status"] != "pooled" {
+			claimed++
+		}
+	}
+	assert.Equal(t, 1+2*requests, claimed)
+}
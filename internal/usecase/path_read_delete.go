@@ -14,13 +14,14 @@ func pathReadAndDelete(b *Backend) *framework.Path {
 		HelpSynopsis: "Create, get or delete a policy by name",
 		HelpDescription: `
 
-    GET - return the key-manager by the name
+    GET - return the key-manager by the name, with its addresses paginated and filtered by the
+          address prefix and the labels of the keys
     DELETE - deletes the key-manager by the name
 
     `,
-		Fields: map[string]*framework.FieldSchema{
+		Fields: withListFields(map[string]*framework.FieldSchema{
 			"name": {Type: framework.TypeString},
-		},
+		}),
 		ExistenceCheck: b.pathExistenceCheck,
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.ReadOperation: &framework.PathOperation{
@@ -43,6 +44,11 @@ func (b *Backend) readKeyManager(
 		return nil, errInvalidType
 	}
 
+	filter, err := newListFilter(data)
+	if err != nil {
+		return nil, err
+	}
+
 	b.Logger().Info("Retrieving key manager for service name", "service_name", serviceName)
 	keyManager, err := b.retrieveKeyManager(ctx, req, serviceName)
 	if err != nil {
@@ -52,17 +58,42 @@ func (b *Backend) readKeyManager(
 		return nil, fmt.Errorf("keyManager does not exist")
 	}
 
-	addresses := make([]string, len(keyManager.KeyPairs))
-	for i := range keyManager.KeyPairs {
-		addresses[i] = keyManager.KeyPairs[i].Address
+	var nextAfter string
+	// addresses are paginated in the order they were added
+	keyPairs := keyManager.KeyPairs
+	if filter.after != "" {
+		i := keyManager.keyPairIndex(filter.after)
+		if i < 0 {
+			return nil, fmt.Errorf("address %s to list after does not exist", filter.after)
+		}
+		keyPairs = keyPairs[i+1:]
 	}
 
-	return &logical.Response{
+	addresses := make([]string, 0)
+	keyInfo := make(map[string]interface{})
+	for i, keyPair := range keyPairs {
+		if filter.full(len(addresses)) {
+			nextAfter = keyPairs[i-1].Address
+			break
+		}
+		if !filter.matchPrefix(keyPair.Address) || !filter.matchLabels(keyPair.Labels) {
+			continue
+		}
+		addresses = append(addresses, keyPair.Address)
+		keyInfo[keyPair.Address] = keyPair.info()
+	}
+
+	resp := &logical.Response{
 		Data: map[string]interface{}{
 			"service_name": keyManager.ServiceName,
 			"addresses":    addresses,
+			"key_info":     keyInfo,
 		},
-	}, nil
+	}
+	if nextAfter != "" {
+		resp.Data["next_after"] = nextAfter
+	}
+	return resp, nil
 }
 
 func (b *Backend) deleteKeyManager(
@@ -75,6 +106,9 @@ func (b *Backend) deleteKeyManager(
 		return nil, errInvalidType
 	}
 
+	b.keyManagerLock.Lock()
+	defer b.keyManagerLock.Unlock()
+
 	policy, err := b.retrieveKeyManager(ctx, req, serviceName)
 	if err != nil {
 		b.Logger().Error("Failed to retrieve the key-manager by service_name",
@@ -91,5 +125,15 @@ func (b *Backend) deleteKeyManager(
 			"service_name", serviceName, "error", err)
 		return nil, err
 	}
+
+	if err = req.Storage.Delete(ctx, poolIndexPath+policy.ServiceName); err != nil {
+		return nil, err
+	}
+
+	if err = req.Storage.Delete(ctx, keyManagerInfoPath+policy.ServiceName); err != nil {
+		return nil, err
+	}
+
+	emitKeyManagerMetric(metricKeyManagerDeleted, policy.ServiceName)
 	return nil, nil
 }
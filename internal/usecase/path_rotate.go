@@ -0,0 +1,200 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathRotate(b *Backend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "key-managers/" + framework.GenericNameRegex("name") + "/rotate",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.rotateKeyPair,
+				},
+			},
+			HelpSynopsis: "Replace a key of the key-manager with a new one.",
+			HelpDescription: `
+
+    POST - retire the key of the address and add its successor, the next derived key of an HD
+    key-manager or a newly generated key. The retired key records its successor and keeps verifying
+    signatures, but it only signs when the key-manager is configured with allowRetiredSigning.
+
+    `,
+			Fields: map[string]*framework.FieldSchema{
+				"name": {Type: framework.TypeString},
+				"address": {
+					Type:        framework.TypeString,
+					Description: "The address of the key to retire.",
+				},
+			},
+		},
+		{
+			Pattern: "key-managers/" + framework.GenericNameRegex("name") + "/verify",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.verify,
+				},
+			},
+			HelpSynopsis: "Verify that a key of the key-manager signed a hash.",
+			HelpDescription: `
+
+    POST - recover the signer of the signature over the hash and report whether it is a key of the
+    key-manager, active or retired.
+
+    `,
+			Fields: map[string]*framework.FieldSchema{
+				"name": {Type: framework.TypeString},
+				"hash": {
+					Type:        framework.TypeString,
+					Description: "Hex string of the hash that was signed.",
+				},
+				"signature": {
+					Type:        framework.TypeString,
+					Description: "Hex string of the 65 byte signature.",
+				},
+				"address": {
+					Type:        framework.TypeString,
+					Description: "(optional) The address that is expected to have signed the hash.",
+				},
+			},
+		},
+	}
+}
+
+func (b *Backend) rotateKeyPair(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	serviceName, ok := data.Get("name").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	address, ok := data.Get("address").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	b.keyManagerLock.Lock()
+	defer b.keyManagerLock.Unlock()
+
+	keyManager, err := b.retrieveKeyManager(ctx, req, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if keyManager == nil {
+		return nil, fmt.Errorf("keyManager does not exist")
+	}
+
+	keyPair := keyManager.keyPair(address)
+	if keyPair == nil {
+		return nil, errors.New("no private key for the input address")
+	}
+
+	successor, err := keyManager.rotate(keyPair)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = b.saveKeyManager(ctx, req, keyManager); err != nil {
+		return nil, err
+	}
+
+	b.Logger().Info("Key rotated", "service_name", serviceName, "retired", keyPair.Address,
+		"successor", successor.Address, "entity_id", req.EntityID)
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"service_name": keyManager.ServiceName,
+			"retired":      keyPair.Address,
+			"address":      successor.Address,
+			"public_key":   successor.PublicKey,
+		},
+	}
+	if successor.DerivationPath != "" {
+		resp.Data["derivation_path"] = successor.DerivationPath
+	}
+	return resp, nil
+}
+
+func (b *Backend) verify(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	serviceName, ok := data.Get("name").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	hashInput, ok := data.Get("hash").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	signatureInput, ok := data.Get("signature").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	address, ok := data.Get("address").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	keyManager, err := b.retrieveKeyManager(ctx, req, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if keyManager == nil {
+		return nil, fmt.Errorf("keyManager does not exist")
+	}
+
+	sig := common.FromHex(signatureInput)
+	if len(sig) != crypto.SignatureLength {
+		return nil, fmt.Errorf("signature must be %d bytes long", crypto.SignatureLength)
+	}
+	// accept the 27/28 recovery IDs of eth_sign as well
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	publicKey, err := crypto.SigToPub(common.HexToHash(hashInput).Bytes(), sig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+	signer := crypto.PubkeyToAddress(*publicKey)
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"service_name": keyManager.ServiceName,
+			"signer":       signer.Hex(),
+			"valid":        false,
+		},
+	}
+
+	if address != "" && common.HexToAddress(address) != signer {
+		return resp, nil
+	}
+
+	keyPair := keyManager.keyPair(signer.Hex())
+	if keyPair == nil {
+		return resp, nil
+	}
+
+	resp.Data["valid"] = true
+	resp.Data["status"] = keyPair.status()
+	if keyPair.Successor != "" {
+		resp.Data["successor"] = keyPair.Successor
+	}
+	return resp, nil
+}
@@ -0,0 +1,165 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackend_rotate(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const (
+		hotWalletSvc = "hot-wallet"
+		address      = "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704"
+		hash         = "0xaf41db230000000000000000000000000000000000000000000000000000000000000023"
+	)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": hotWalletSvc,
+		"privateKey":  "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	sign := func(address string) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+hotWalletSvc+"/sign")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"hash":    hash,
+			"address": address,
+		}
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	verify := func(signature string) *logical.Response {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+hotWalletSvc+"/verify")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"hash":      hash,
+			"signature": signature,
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return resp
+	}
+
+	rotate := func(address string) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+hotWalletSvc+"/rotate")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"address": address,
+		}
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	resp, err := sign(address)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	oldSignature := resp.Data["signature"].(string)
+
+	resp, err = rotate(address)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, address, resp.Data["retired"])
+	successor := resp.Data["address"].(string)
+	assert.NotEqual(t, address, successor)
+
+	_, err = rotate(address)
+	assert.EqualError(t, err, "address "+address+" was already retired and succeeded by "+successor)
+
+	// the retired key no longer signs, its successor does
+	_, err = sign(address)
+	assert.EqualError(t, err, "address "+address+" was retired and succeeded by "+successor+
+		", set allowRetiredSigning to sign with it")
+
+	resp, err = sign(successor)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	newSignature := resp.Data["signature"].(string)
+
+	// signatures of both keys still verify
+	resp = verify(oldSignature)
+	assert.Equal(t, true, resp.Data["valid"])
+	assert.Equal(t, "retired", resp.Data["status"])
+	assert.Equal(t, successor, resp.Data["successor"])
+
+	resp = verify(newSignature)
+	assert.Equal(t, true, resp.Data["valid"])
+	assert.Equal(t, "active", resp.Data["status"])
+
+	otherKey, _ := crypto.GenerateKey()
+	otherSignature, _ := crypto.Sign(common.HexToHash(hash).Bytes(), otherKey)
+	resp = verify(common.Bytes2Hex(otherSignature))
+	assert.Equal(t, false, resp.Data["valid"])
+
+	// the read response records the succession
+	req = logical.TestRequest(t, logical.ReadOperation, "key-managers/"+hotWalletSvc)
+	req.Storage = storage
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	keyInfo := resp.Data["key_info"].(map[string]interface{})
+	assert.Equal(t, "retired", keyInfo[address].(map[string]interface{})["status"])
+	assert.Equal(t, successor, keyInfo[address].(map[string]interface{})["successor"])
+	assert.Equal(t, address, keyInfo[successor].(map[string]interface{})["predecessor"])
+
+	// sweeping the old address needs retired signing to be allowed
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+hotWalletSvc+"/config")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"allowRetiredSigning": true,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp, err = sign(address)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, oldSignature, resp.Data["signature"])
+}
+
+func TestBackend_rotateHD(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": "hd-service",
+		"mnemonic":    "test test test test test test test test test test test junk",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/hd-service/rotate")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"address": "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assert.Equal(t, "0x70997970C51812dc3A010C7d01b50e0d17dc79C8", resp.Data["address"])
+	assert.Equal(t, "m/44'/60'/0'/0/1", resp.Data["derivation_path"])
+}
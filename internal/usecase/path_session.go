@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathSession(b *Backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "key-managers/" + framework.GenericNameRegex("name") + "/session",
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.createSessionKey,
+			},
+		},
+		HelpSynopsis: "Create a session key that is destroyed when its lease ends.",
+		HelpDescription: `
+
+    GET - generate a new signing key in the key-manager and return its address with a Vault lease.
+    The key signs like any other key of the key-manager until the lease expires or is revoked,
+    which destroys it.
+
+    `,
+		Fields: map[string]*framework.FieldSchema{
+			"name": {Type: framework.TypeString},
+			"ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "(optional, default: 1h) The TTL of the lease.",
+			},
+		},
+	}
+}
+
+func (b *Backend) createSessionKey(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	serviceName, ok := data.Get("name").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	ttl := time.Duration(data.Get("ttl").(int)) * time.Second
+	if ttl < 0 {
+		return nil, fmt.Errorf("ttl cannot be negative")
+	}
+
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(privateKey)
+
+	// The key pair expires at the max lease TTL on its own, so it stops signing even if a renewal
+	// slipped past the lease or the revocation failed.
+	maxTTL := b.System().MaxLeaseTTL()
+	if maxTTL <= 0 {
+		maxTTL = defaultSessionKeyMaxTTL
+	}
+	if ttl > maxTTL {
+		ttl = maxTTL
+	}
+	notAfter := time.Now().UTC().Add(maxTTL)
+
+	keyPair := newKeyPair(privateKey)
+	keyPair.Session = true
+	keyPair.NotAfter = &notAfter
+
+	b.keyManagerLock.Lock()
+	defer b.keyManagerLock.Unlock()
+
+	keyManager, err := b.retrieveKeyManager(ctx, req, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if keyManager == nil {
+		return nil, fmt.Errorf("keyManager does not exist")
+	}
+
+	if err = b.checkIdentity(keyManager, req.EntityID); err != nil {
+		return nil, err
+	}
+
+	keyManager.KeyPairs = append(keyManager.KeyPairs, keyPair)
+	if err = b.saveKeyManager(ctx, req, keyManager); err != nil {
+		return nil, err
+	}
+
+	resp := b.Secret(secretTypeSessionKey).Response(map[string]interface{}{
+		"service_name": keyManager.ServiceName,
+		"address":      keyPair.Address,
+		"public_key":   keyPair.PublicKey,
+		"not_after":    notAfter.Format(time.RFC3339),
+	}, map[string]interface{}{
+		"service_name": keyManager.ServiceName,
+		"address":      keyPair.Address,
+	})
+	if ttl > 0 {
+		resp.Secret.TTL = ttl
+	}
+	resp.Secret.MaxTTL = maxTTL
+	return resp, nil
+}
@@ -0,0 +1,165 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackend_sessionKey(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const botSvc = "trading-bot"
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": botSvc,
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "key-managers/"+botSvc+"/session")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"ttl": 600,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.NotNil(t, resp.Secret)
+	assert.Equal(t, 10*time.Minute, resp.Secret.TTL)
+	assert.Equal(t, defaultSessionKeyMaxTTL, resp.Secret.MaxTTL)
+	assert.NotEmpty(t, resp.Data["not_after"])
+	secret := resp.Secret
+	address := resp.Data["address"].(string)
+
+	sign := func() error {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+botSvc+"/sign")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"hash":    "0xaf41db230000000000000000000000000000000000000000000000000000000000000023",
+			"address": address,
+		}
+		_, err := b.HandleRequest(context.Background(), req)
+		return err
+	}
+
+	assert.NoError(t, sign())
+
+	req = &logical.Request{
+		Operation: logical.RenewOperation,
+		Storage:   storage,
+		Secret:    secret,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	assert.NoError(t, err)
+
+	// revoking the lease destroys the key
+	req = &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   storage,
+		Secret:    secret,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assert.EqualError(t, sign(), "no private key for the input address")
+
+	req = logical.TestRequest(t, logical.ReadOperation, "key-managers/"+botSvc)
+	req.Storage = storage
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Len(t, resp.Data["addresses"], 1)
+	assert.NotContains(t, resp.Data["addresses"], address)
+
+	req = &logical.Request{
+		Operation: logical.RenewOperation,
+		Storage:   storage,
+		Secret:    secret,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	assert.EqualError(t, err, "session key "+address+" of keyManager "+botSvc+" no longer exists")
+}
+
+func TestBackend_sessionKeyMaxTTL(t *testing.T) {
+	b, err := Factory(context.Background(), &logical.BackendConfig{
+		System:      &logical.StaticSystemView{MaxLeaseTTLVal: time.Second},
+		StorageView: &logical.InmemStorage{},
+		BackendUUID: "test",
+	})
+	if err != nil {
+		t.Fatalf("unable to create backend: %v", err)
+	}
+	time.Sleep(time.Second)
+
+	const botSvc = "trading-bot"
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": botSvc,
+	}
+	if _, err = b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "key-managers/"+botSvc+"/session")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"ttl": 600,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	// the lease is capped at the max lease TTL
+	assert.Equal(t, time.Second, resp.Secret.TTL)
+	assert.Equal(t, time.Second, resp.Secret.MaxTTL)
+	secret := resp.Secret
+	address := resp.Data["address"].(string)
+
+	sign := func() error {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+botSvc+"/sign")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"hash":    "0xaf41db230000000000000000000000000000000000000000000000000000000000000023",
+			"address": address,
+		}
+		_, err := b.HandleRequest(context.Background(), req)
+		return err
+	}
+
+	assert.NoError(t, sign())
+
+	// the key stops signing at the max TTL even though the lease was never revoked
+	time.Sleep(1100 * time.Millisecond)
+	assert.ErrorContains(t, sign(), "address "+address+" expired at ")
+
+	req = &logical.Request{
+		Operation: logical.RenewOperation,
+		Storage:   storage,
+		Secret:    secret,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	assert.ErrorContains(t, err, "address "+address+" expired at ")
+}
+
+func TestBackend_sessionKeyFailure1(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "key-managers/missing/session")
+	_, err := b.HandleRequest(context.Background(), req)
+
+	assert.EqualError(t, err, "keyManager does not exist")
+}
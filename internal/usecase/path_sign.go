@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -13,10 +15,9 @@ import (
 
 func pathSign(b *Backend) *framework.Path {
 	return &framework.Path{
-		Pattern:        "key-managers/" + framework.GenericNameRegex("name") + "/sign",
-		ExistenceCheck: b.pathExistenceCheck,
+		Pattern: "key-managers/" + framework.GenericNameRegex("name") + "/sign",
 		Operations: map[logical.Operation]framework.OperationHandler{
-			logical.CreateOperation: &framework.PathOperation{
+			logical.UpdateOperation: &framework.PathOperation{
 				Callback: b.sign,
 			},
 		},
@@ -37,6 +38,10 @@ func pathSign(b *Backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: "The address that belongs to a private key in the key-manager.",
 			},
+			"idempotencyKey": {
+				Type:        framework.TypeString,
+				Description: "(optional) Client-supplied key. Retrying a request with the same key returns the stored signature instead of a new one.",
+			},
 		},
 	}
 }
@@ -45,6 +50,20 @@ func (b *Backend) sign(
 	ctx context.Context,
 	req *logical.Request,
 	data *framework.FieldData,
+) (*logical.Response, error) {
+	record := newAuditRecord(req, operationSign)
+	defer emitSigningMetrics(record, time.Now())
+
+	resp, err := b.handleSign(ctx, req, data, record)
+	b.recordAudit(ctx, req, record, resp, err)
+	return resp, err
+}
+
+func (b *Backend) handleSign(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+	record *AuditRecord,
 ) (*logical.Response, error) {
 	serviceNameInput, ok := data.Get("name").(string)
 	if !ok {
@@ -61,6 +80,10 @@ func (b *Backend) sign(
 		return nil, errInvalidType
 	}
 
+	record.ServiceName = serviceNameInput
+	record.Address = address
+	record.Hash = hashInput
+
 	keyManager, err := b.retrieveKeyManager(ctx, req, serviceNameInput)
 	if err != nil {
 		b.Logger().Error("Failed to retrieve the signing keyManager",
@@ -76,19 +99,60 @@ func (b *Backend) sign(
 		return nil, fmt.Errorf("signing keyManager %s does not have a key pair", serviceNameInput)
 	}
 
-	var privateKeyStr string
-	for _, keyPairs := range keyManager.KeyPairs {
-		if keyPairs.Address == address {
-			privateKeyStr = keyPairs.PrivateKey
-			break
-		}
+	if err = b.checkIdentity(keyManager, req.EntityID); err != nil {
+		return nil, err
+	}
+
+	config, err := b.retrieveConfig(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if config.DisableHashSigning {
+		return nil, errors.New("signing raw hashes is disabled on this mount")
+	}
+
+	if err = keyManager.checkPurpose(purposeHash); err != nil {
+		return nil, err
 	}
 
-	if privateKeyStr == "" {
+	if err = b.enforceRateLimits(ctx, req, keyManager, address); err != nil {
+		return nil, err
+	}
+
+	_, err = b.enforcePolicies(ctx, req, keyManager, &policyInput{
+		operation:   operationSign,
+		serviceName: serviceNameInput,
+		address:     address,
+		hash:        hashInput,
+		entityID:    req.EntityID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keyPair := keyManager.keyPair(address)
+	if keyPair == nil {
 		return nil, errors.New("no private key for the input address")
 	}
 
-	privateKey, err := crypto.HexToECDSA(privateKeyStr)
+	if err = keyManager.checkSigningKey(keyPair); err != nil {
+		return nil, err
+	}
+
+	idempotencyKey := data.Get("idempotencyKey").(string)
+	fingerprint := fmt.Sprintf("%s:%s:%s", operationSign, strings.ToLower(address), common.HexToHash(hashInput).Hex())
+	if idempotencyKey != "" {
+		resp, err := b.replayIdempotent(ctx, req, keyManager.ServiceName, idempotencyKey, fingerprint)
+		if err != nil || resp != nil {
+			return resp, err
+		}
+	}
+
+	if err = b.countSignature(ctx, req, keyManager, keyPair); err != nil {
+		return nil, err
+	}
+
+	privateKey, err := keyManager.privateKey(keyPair)
 	if err != nil {
 		b.Logger().Error("Error reconstructing private key from retrieved hex", "error", err)
 		return nil, fmt.Errorf("error reconstructing private key from retrieved hex")
@@ -105,9 +169,16 @@ func (b *Backend) sign(
 		return nil, fmt.Errorf("error reconstructing private key from retrieved hex")
 	}
 
-	return &logical.Response{
+	resp := &logical.Response{
 		Data: map[string]interface{}{
 			"signature": common.Bytes2Hex(sig),
 		},
-	}, nil
+	}
+
+	if idempotencyKey != "" {
+		if err = b.saveIdempotent(ctx, req, keyManager.ServiceName, idempotencyKey, fingerprint, resp); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
 }
@@ -3,7 +3,9 @@ package usecase
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/armon/go-metrics"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -37,7 +39,7 @@ func TestBackend_sign(t *testing.T) {
 	// sign contract creation TX by address using Homestead signer
 	dataToSign := []byte("data that should be signed")
 	hash := crypto.Keccak256Hash(dataToSign)
-	req = logical.TestRequest(t, logical.CreateOperation, "key-managers/"+testSvc+"/sign")
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+testSvc+"/sign")
 	req.Storage = storage
 	data := map[string]interface{}{
 		"hash":    hash.Hex(),
@@ -58,3 +60,53 @@ func TestBackend_sign(t *testing.T) {
 
 	assert.Equal(t, sigPublicKey, publicKeyBytes)
 }
+
+func TestBackend_signMetrics(t *testing.T) {
+	sink := metrics.NewInmemSink(time.Minute, time.Minute)
+	conf := metrics.DefaultConfig("vault")
+	conf.EnableHostname = false
+	conf.EnableRuntimeMetrics = false
+	if _, err := metrics.NewGlobal(conf, sink); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer metrics.NewGlobal(metrics.DefaultConfig(""), &metrics.BlackholeSink{}) //nolint:errcheck
+
+	b, _ := newTestBackend(t)
+
+	const testSvc = "test-service"
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": testSvc,
+		"privateKey":  "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	sign := func(address string) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+testSvc+"/sign")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"hash":    "0xaf41db230000000000000000000000000000000000000000000000000000000000000023",
+			"address": address,
+		}
+		_, _ = b.HandleRequest(context.Background(), req)
+	}
+
+	sign("0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704")
+	sign("0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704")
+	sign("0x0000000000000000000000000000000000000001")
+
+	interval := sink.Data()[0]
+	interval.RLock()
+	defer interval.RUnlock()
+
+	const requests = "vault.ethsigner.sign.requests;operation=sign;service_name=" + testSvc
+	assert.Equal(t, 2, interval.Counters[requests+";decision=signed"].Count)
+	assert.Equal(t, 1, interval.Counters[requests+";decision=rejected"].Count)
+	assert.Equal(t, 1, interval.Counters["vault.ethsigner.key_manager.created;service_name="+testSvc].Count)
+	assert.Equal(t, 2, interval.Samples["vault.ethsigner.sign.duration;operation=sign;service_name="+testSvc+";decision=signed"].Count)
+}
@@ -6,28 +6,31 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
 type RequestFieldsTransaction struct {
-	tx      *types.Transaction
-	chainID *big.Int
-	from    string
-	address string
+	tx           *types.Transaction
+	chainID      *big.Int
+	from         string
+	address      string
+	derivationID string
+	replacement  bool
 }
 
 func pathSignTx(b *Backend) *framework.Path {
 	return &framework.Path{
-		Pattern:        "key-managers/" + framework.GenericNameRegex("name") + "/txn/sign",
-		ExistenceCheck: b.pathExistenceCheck,
+		Pattern: "key-managers/" + framework.GenericNameRegex("name") + "/txn/sign",
 		Operations: map[logical.Operation]framework.OperationHandler{
-			logical.CreateOperation: &framework.PathOperation{
+			logical.UpdateOperation: &framework.PathOperation{
 				Callback: b.signTx,
 			},
 		},
@@ -66,8 +69,7 @@ func pathSignTx(b *Backend) *framework.Path {
 			},
 			"gas": {
 				Type:        framework.TypeString,
-				Description: "(optional, default: 90000) Integer of the gas provided for the transaction execution. It will return unused gas",
-				Default:     "90000",
+				Description: "(optional, default: the defaultGasLimit of the mount, 90000) Integer of the gas provided for the transaction execution. It will return unused gas",
 			},
 			"gasPrice": {
 				Type:        framework.TypeString,
@@ -84,8 +86,19 @@ func pathSignTx(b *Backend) *framework.Path {
 			},
 			"chainId": {
 				Type:        framework.TypeString,
-				Description: "(optional) Chain ID of the target blockchain network. If present, EIP155 signer will be used to sign. If omitted, Homestead signer will be used.",
-				Default:     "0",
+				Description: "(optional, default: the defaultChainId of the mount) Chain ID of the target blockchain network. If present, EIP155 signer will be used to sign. If omitted without a default, Homestead signer will be used, which is rejected unless unprotected transactions are allowed.",
+			},
+			"idempotencyKey": {
+				Type:        framework.TypeString,
+				Description: "(optional) Client-supplied key. Retrying a request with the same key returns the stored response instead of a new one.",
+			},
+			"derivationId": {
+				Type:        framework.TypeString,
+				Description: "(optional) Sign with the key an HD key-manager derives for this identifier instead of address.",
+			},
+			"replacement": {
+				Type:        framework.TypeBool,
+				Description: "(optional) Replace (speed up or cancel) the transaction already signed with this nonce. The replacement must pay strictly higher fees.",
 			},
 		},
 	}
@@ -96,11 +109,34 @@ func (b *Backend) signTx(
 	req *logical.Request,
 	data *framework.FieldData,
 ) (*logical.Response, error) {
-	feildsAndTx, err := b.validateAndGetTx(data)
+	record := newAuditRecord(req, operationSignTx)
+	defer emitSigningMetrics(record, time.Now())
+
+	resp, err := b.handleSignTx(ctx, req, data, record)
+	b.recordAudit(ctx, req, record, resp, err)
+	return resp, err
+}
+
+func (b *Backend) handleSignTx(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+	record *AuditRecord,
+) (*logical.Response, error) {
+	config, err := b.retrieveConfig(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	feildsAndTx, err := b.validateAndGetTx(data, config)
 	if err != nil {
 		return nil, err
 	}
 
+	record.ServiceName = feildsAndTx.from
+	record.Address = feildsAndTx.address
+	record.setTx(feildsAndTx.tx, feildsAndTx.chainID)
+
 	keyManager, err := b.retrieveKeyManager(ctx, req, feildsAndTx.from)
 	if err != nil {
 		b.Logger().Error("Failed to retrieve the signing keyManager",
@@ -112,33 +148,135 @@ func (b *Backend) signTx(
 		return nil, fmt.Errorf("signing keyManager %s does not exist", feildsAndTx.from)
 	}
 
-	var privateKeyStr string
-	for _, keyPairs := range keyManager.KeyPairs {
-		if keyPairs.Address == feildsAndTx.address {
-			privateKeyStr = keyPairs.PrivateKey
-			break
+	if err = b.checkIdentity(keyManager, req.EntityID); err != nil {
+		return nil, err
+	}
+
+	if err = keyManager.checkPurpose(purposeTransaction); err != nil {
+		return nil, err
+	}
+
+	keyPair, err := keyManager.resolveKeyPair(feildsAndTx.address, feildsAndTx.derivationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = keyManager.checkSigningKey(keyPair); err != nil {
+		return nil, err
+	}
+	feildsAndTx.address = keyPair.Address
+	record.Address = keyPair.Address
+
+	if err = b.enforceRateLimits(ctx, req, keyManager, keyPair.Address); err != nil {
+		return nil, err
+	}
+
+	if err = checkChainID(config, keyManager, feildsAndTx.chainID); err != nil {
+		return nil, err
+	}
+
+	effect, err := b.enforcePolicies(ctx, req, keyManager, &policyInput{
+		operation:   operationSignTx,
+		serviceName: feildsAndTx.from,
+		address:     feildsAndTx.address,
+		tx:          feildsAndTx.tx,
+		chainID:     feildsAndTx.chainID,
+		entityID:    req.EntityID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	idempotencyKey := data.Get("idempotencyKey").(string)
+	fingerprint := fmt.Sprintf("%s:%s:%s", operationSignTx, strings.ToLower(feildsAndTx.address),
+		sigHash(feildsAndTx.tx, feildsAndTx.chainID))
+	if idempotencyKey != "" {
+		resp, err := b.replayIdempotent(ctx, req, keyManager.ServiceName, idempotencyKey, fingerprint)
+		if err != nil || resp != nil {
+			return resp, err
+		}
+	}
+
+	// fail early instead of queueing a transaction that conflicts with an already signed nonce
+	if err = b.checkNonce(ctx, req, feildsAndTx.address, feildsAndTx.tx, feildsAndTx.chainID,
+		feildsAndTx.replacement); err != nil {
+		return nil, err
+	}
+
+	var resp *logical.Response
+	switch effect {
+	case policyEffectRequireApproval:
+		resp, err = b.queueForApproval(ctx, req, keyManager, feildsAndTx)
+	case policyEffectDelay:
+		resp, err = b.queueWithTimeLock(ctx, req, keyManager, feildsAndTx)
+	default:
+		resp, err = b.signTxNow(ctx, req, keyManager, keyPair, feildsAndTx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if idempotencyKey != "" {
+		if err = b.saveIdempotent(ctx, req, keyManager.ServiceName, idempotencyKey, fingerprint, resp); err != nil {
+			return nil, err
 		}
 	}
+	return resp, nil
+}
+
+func (b *Backend) signTxNow(
+	ctx context.Context,
+	req *logical.Request,
+	keyManager *KeyManager,
+	keyPair *KeyPair,
+	fields *RequestFieldsTransaction,
+) (*logical.Response, error) {
+	signed, replayed, err := b.signAndRecordTransaction(ctx, req, keyManager, keyPair, fields.tx, fields.chainID,
+		fields.replacement)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{
+		Data: signed.responseData(),
+	}
+	if replayed {
+		resp.Data["replayed"] = true
+	}
+	return resp, nil
+}
+
+// SignedTransaction is the outcome of signing a transaction.
+type SignedTransaction struct {
+	TxHash     string `json:"tx_hash"`
+	SignedTx   string `json:"signed_tx"`
+	SignerType string `json:"signer_type"`
+}
 
-	if privateKeyStr == "" {
-		return nil, errors.New("no private key for the input address")
+func (s *SignedTransaction) responseData() map[string]interface{} {
+	return map[string]interface{}{
+		"txHash":     s.TxHash,
+		"signedTx":   s.SignedTx,
+		"signerType": s.SignerType,
 	}
+}
 
-	privateKey, err := crypto.HexToECDSA(privateKeyStr)
+func (b *Backend) signTransaction(
+	keyManager *KeyManager,
+	keyPair *KeyPair,
+	tx *types.Transaction,
+	chainID *big.Int,
+) (*SignedTransaction, error) {
+	privateKey, err := keyManager.privateKey(keyPair)
 	if err != nil {
 		b.Logger().Error("Error reconstructing private key from retrieved hex", "error", err)
 		return nil, fmt.Errorf("error reconstructing private key from retrieved hex")
 	}
 	defer zeroKey(privateKey)
 
-	var signer types.Signer
-	if big.NewInt(0).Cmp(feildsAndTx.chainID) == 0 {
-		signer = types.HomesteadSigner{}
-	} else {
-		signer = types.LatestSignerForChainID(feildsAndTx.chainID)
-	}
+	signer, signerType := signerForTx(tx, chainID)
 
-	signedTx, err := types.SignTx(feildsAndTx.tx, signer, privateKey)
+	signedTx, err := types.SignTx(tx, signer, privateKey)
 	if err != nil {
 		b.Logger().Error("Failed to sign the transaction object", "error", err)
 		return nil, err
@@ -151,15 +289,16 @@ func (b *Backend) signTx(
 		return nil, err
 	}
 
-	return &logical.Response{
-		Data: map[string]interface{}{
-			"txHash":   signedTx.Hash().Hex(),
-			"signedTx": hexutil.Encode(signedTxBuff.Bytes()),
-		},
+	return &SignedTransaction{
+		TxHash:     signedTx.Hash().Hex(),
+		SignedTx:   hexutil.Encode(signedTxBuff.Bytes()),
+		SignerType: signerType,
 	}, nil
 }
 
-func (b *Backend) validateAndGetTx(data *framework.FieldData) (*RequestFieldsTransaction, error) {
+// validateAndGetTx builds the transaction of the request, filling in the chain ID and gas limit
+// defaults of the mount.
+func (b *Backend) validateAndGetTx(data *framework.FieldData, config *Config) (*RequestFieldsTransaction, error) {
 	from, ok := data.Get("name").(string)
 	if !ok {
 		return nil, errInvalidType
@@ -185,7 +324,7 @@ func (b *Backend) validateAndGetTx(data *framework.FieldData) (*RequestFieldsTra
 
 	txDataToSign, err := hexutil.Decode(dataInput)
 	if err != nil {
-		b.Logger().Error("Failed to decode payload for the 'data' field", "error", err)
+		b.Logger().Error("Failed to decode payload for the 'data' field", "data", config.redact(dataInput), "error", err)
 		return nil, err
 	}
 
@@ -209,15 +348,23 @@ func (b *Backend) validateAndGetTx(data *framework.FieldData) (*RequestFieldsTra
 		return nil, errInvalidType
 	}
 
-	chainID := validNumber(data.Get("chainId").(string))
+	chainIDInput := data.Get("chainId").(string)
+	if chainIDInput == "" {
+		chainIDInput = config.DefaultChainID
+	}
+	chainID := validNumber(chainIDInput)
 	if chainID == nil {
-		b.Logger().Error("Invalid chainId", "chainId", data.Get("chainId").(string))
+		b.Logger().Error("Invalid chainId", "chainId", chainIDInput)
 		return nil, fmt.Errorf("invalid chainId value")
 	}
 
-	gasLimitIn := validNumber(data.Get("gas").(string))
+	gasInput := data.Get("gas").(string)
+	if gasInput == "" {
+		gasInput = strconv.FormatUint(config.gasLimit(), 10)
+	}
+	gasLimitIn := validNumber(gasInput)
 	if gasLimitIn == nil {
-		b.Logger().Error("Invalid gas limit", "gas", data.Get("gas").(string))
+		b.Logger().Error("Invalid gas limit", "gas", gasInput)
 		return nil, fmt.Errorf("invalid gas limit")
 	}
 
@@ -240,9 +387,11 @@ func (b *Backend) validateAndGetTx(data *framework.FieldData) (*RequestFieldsTra
 	}
 
 	out := &RequestFieldsTransaction{
-		address: address,
-		from:    from,
-		chainID: chainID,
+		address:      address,
+		from:         from,
+		chainID:      chainID,
+		derivationID: data.Get("derivationId").(string),
+		replacement:  data.Get("replacement").(bool),
 	}
 
 	if gasFeeCapStr != "" && gasTipCapStr != "" {
@@ -255,3 +404,47 @@ func (b *Backend) validateAndGetTx(data *framework.FieldData) (*RequestFieldsTra
 
 	return out, nil
 }
+
+// checkChainID enforces the chain ID allowlists of the mount and the key-manager, which can only
+// narrow the mount allowlist, and rejects replayable pre-EIP155 transactions unless both the mount
+// and the key-manager permit them.
+func checkChainID(config *Config, keyManager *KeyManager, chainID *big.Int) error {
+	if chainID.Sign() == 0 {
+		if !config.AllowUnprotected || !keyManager.AllowUnprotected {
+			return errors.New("chainId is required: unprotected (pre-EIP155) transactions are not permitted")
+		}
+		return nil
+	}
+
+	if !chainIDAllowed(config.AllowedChainIDs, chainID) || !chainIDAllowed(keyManager.AllowedChainIDs, chainID) {
+		return fmt.Errorf("chainId %s is not allowed for keyManager %s", chainID, keyManager.ServiceName)
+	}
+	return nil
+}
+
+// chainIDAllowed reports whether the chain ID is in the allowlist. An empty allowlist allows every
+// chain ID.
+func chainIDAllowed(allowed []string, chainID *big.Int) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, id := range allowed {
+		if id == chainID.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// signerForTx returns the signer for the transaction and the name of its signature scheme.
+func signerForTx(tx *types.Transaction, chainID *big.Int) (types.Signer, string) {
+	if chainID.Sign() == 0 {
+		return types.HomesteadSigner{}, "homestead"
+	}
+
+	signer := types.LatestSignerForChainID(chainID)
+	if tx.Type() == types.DynamicFeeTxType {
+		return signer, "eip1559"
+	}
+	return signer, "eip155"
+}
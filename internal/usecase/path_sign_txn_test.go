@@ -73,7 +73,7 @@ func TestBackend_signTx(t *testing.T) {
 
 	// sign contract creation TX by address using Homestead signer
 	dataToSign := "608060405234801561001057600080fd5b506040516020806101d783398101604052516000556101a3806100346000396000f3006080604052600436106100615763ffffffff7c01000000000000000000000000000000000000000000000000000000006000350416632a1afcd981146100665780632c46b2051461008d57806360fe47b1146100a25780636d4ce63c1461008d575b600080fd5b34801561007257600080fd5b5061007b6100ba565b60408051918252519081900360200190f35b34801561009957600080fd5b5061007b6100c0565b3480156100ae57600080fd5b5061007b6004356100c6565b60005481565b60005490565b60006064821061013757604080517f08c379a000000000000000000000000000000000000000000000000000000000815260206004820152601960248201527f56616c75652063616e206e6f74206265206f7665722031303000000000000000604482015290519081900360640190fd5b60008290556040805183815290517f9455957c3b77d1d4ed071e2b469dd77e37fc5dfd3b4d44dc8a997cc97c7b3d499181900360200190a15050600054905600a165627a7a72305820a22d4674e519555e6f065ccf98b5bd479e108895cbddc10cba200c775d0008730029000000000000000000000000000000000000000000000000000000000000000a"
-	req = logical.TestRequest(t, logical.CreateOperation, "key-managers/"+keeperSvc+"/txn/sign")
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+keeperSvc+"/txn/sign")
 	req.Storage = storage
 	data := map[string]interface{}{
 		"address":  "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704",
@@ -83,10 +83,39 @@ func TestBackend_signTx(t *testing.T) {
 		"gasPrice": 0,
 	}
 	req.Data = data
+	_, err = b.HandleRequest(context.Background(), req)
+	assert.ErrorContains(t, err, "unprotected (pre-EIP155) transactions are not permitted")
+
+	// explicitly permit unprotected transactions for the key-manager, which the mount has to
+	// permit as well
+	configReq := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+keeperSvc+"/config")
+	configReq.Storage = storage
+	configReq.Data = map[string]interface{}{
+		"allowUnprotected": true,
+	}
+	_, err = b.HandleRequest(context.Background(), configReq)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	_, err = b.HandleRequest(context.Background(), req)
+	assert.ErrorContains(t, err, "unprotected (pre-EIP155) transactions are not permitted")
+
+	configReq = logical.TestRequest(t, logical.UpdateOperation, "config")
+	configReq.Storage = storage
+	configReq.Data = map[string]interface{}{
+		"allowUnprotected": true,
+	}
+	_, err = b.HandleRequest(context.Background(), configReq)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
 	resp, err = b.HandleRequest(context.Background(), req)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+	assert.Equal(t, "homestead", resp.Data["signerType"])
 
 	tx := &types.Transaction{}
 	signedTx := resp.Data["signedTx"].(string)
@@ -107,7 +136,7 @@ func TestBackend_signTx(t *testing.T) {
 
 	// sign TX by address without "0x" using EIP155 signer
 	dataToSign = "60fe47b10000000000000000000000000000000000000000000000000000000000000014"
-	req = logical.TestRequest(t, logical.CreateOperation, "key-managers/"+keeperSvc+"/txn/sign")
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+keeperSvc+"/txn/sign")
 	req.Storage = storage
 	data = map[string]interface{}{
 		"data":     dataToSign,
@@ -137,6 +166,7 @@ func TestBackend_signTx(t *testing.T) {
 
 	v, _, _ = tx.RawSignatureValues()
 	assert.True(t, contains([]*big.Int{big.NewInt(24725), big.NewInt(24726)}, v))
+	assert.Equal(t, "eip155", resp.Data["signerType"])
 
 	sender, _ = types.Sender(types.LatestSignerForChainID(big.NewInt(12345)), tx)
 	assert.Equal(t, address.Hex(), sender.Hex())
@@ -170,13 +200,14 @@ func TestBackend_signTx(t *testing.T) {
 
 	v, _, _ = tx.RawSignatureValues()
 	assert.True(t, v.Cmp(big.NewInt(1)) == 0)
+	assert.Equal(t, "eip1559", resp.Data["signerType"])
 
 	sender, _ = types.Sender(types.LatestSignerForChainID(big.NewInt(1)), tx)
 	assert.Equal(t, address.Hex(), sender.Hex())
 
 	// sign TX with invalid nonce
 	dataToSign = "60fe47b10000000000000000000000000000000000000000000000000000000000000014"
-	req = logical.TestRequest(t, logical.CreateOperation, "key-managers/"+keeperSvc+"/txn/sign")
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+keeperSvc+"/txn/sign")
 	req.Storage = storage
 	data = map[string]interface{}{
 		"data":     dataToSign,
@@ -191,3 +222,147 @@ func TestBackend_signTx(t *testing.T) {
 	_, err = b.HandleRequest(context.Background(), req)
 	assert.ErrorContains(t, err, "invalid nonce")
 }
+
+func TestBackend_signTxChainIDAllowlist(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const (
+		keeperSvc  = "keeper-service"
+		privateKey = "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1"
+	)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": keeperSvc,
+		"privateKey":  privateKey,
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "config")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"allowedChainIds":  "1,0x89",
+		"allowUnprotected": true,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, []string{"1", "137"}, resp.Data["allowed_chain_ids"])
+
+	signReq := func(chainID string) error {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+keeperSvc+"/txn/sign")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"address":  "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704",
+			"data":     "0x",
+			"to":       "0xf809410b0d6f047c603deb311979cd413e025a84",
+			"gas":      21000,
+			"nonce":    "0x1",
+			"gasPrice": 1,
+			"chainId":  chainID,
+		}
+		_, err := b.HandleRequest(context.Background(), req)
+		return err
+	}
+
+	assert.NoError(t, signReq("1"))
+	assert.NoError(t, signReq("137"))
+	assert.ErrorContains(t, signReq("0"), "unprotected (pre-EIP155) transactions are not permitted")
+	assert.ErrorContains(t, signReq("10"), "chainId 10 is not allowed")
+
+	// the key-manager allowlist narrows the mount allowlist, chain IDs outside of it stay forbidden
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+keeperSvc+"/config")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"allowedChainIds":  []string{"10", "137"},
+		"allowUnprotected": true,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assert.NoError(t, signReq("137"))
+	assert.NoError(t, signReq("0"))
+	assert.ErrorContains(t, signReq("10"), "chainId 10 is not allowed")
+	assert.ErrorContains(t, signReq("1"), "chainId 1 is not allowed")
+}
+
+func TestBackend_signTxConflictingNonce(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const (
+		keeperSvc  = "keeper-service"
+		privateKey = "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1"
+	)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": keeperSvc,
+		"privateKey":  privateKey,
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	signReq := func(data map[string]interface{}) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+keeperSvc+"/txn/sign")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"address": "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704",
+			"data":    "0x",
+			"to":      "0xf809410b0d6f047c603deb311979cd413e025a84",
+			"gas":     21000,
+			"nonce":   "0x5",
+			"chainId": "1",
+		}
+		for k, v := range data {
+			req.Data[k] = v
+		}
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	first, err := signReq(map[string]interface{}{"value": "1000", "gasPrice": 10})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// the identical transaction is not a conflict
+	_, err = signReq(map[string]interface{}{"value": "1000", "gasPrice": 10})
+	assert.NoError(t, err)
+
+	_, err = signReq(map[string]interface{}{"value": "2000", "gasPrice": 10})
+	assert.EqualError(t, err, "nonce 5 on chainId 1 was already signed for transaction "+
+		first.Data["txHash"].(string)+", set replacement to replace it")
+
+	// the same nonce on another chain is unrelated
+	_, err = signReq(map[string]interface{}{"value": "2000", "gasPrice": 10, "chainId": "137"})
+	assert.NoError(t, err)
+
+	_, err = signReq(map[string]interface{}{"value": "0", "gasPrice": 10, "replacement": true})
+	assert.ErrorContains(t, err, "must pay strictly higher fees")
+
+	cancel, err := signReq(map[string]interface{}{"value": "0", "gasPrice": 11, "replacement": true})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// a dynamic fee replacement has to raise both the fee cap and the tip over the legacy gas price
+	_, err = signReq(map[string]interface{}{
+		"value": "0", "gasFeeCap": "20", "gasTipCap": "11", "replacement": true,
+	})
+	assert.EqualError(t, err, "replacement for nonce 5 on chainId 1 must pay strictly higher fees than transaction "+
+		cancel.Data["txHash"].(string))
+
+	_, err = signReq(map[string]interface{}{
+		"value": "0", "gasFeeCap": "20", "gasTipCap": "12", "replacement": true,
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathSignTypedData(b *Backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "key-managers/" + framework.GenericNameRegex("name") + "/typed-data/sign",
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.signTypedData,
+			},
+		},
+		HelpSynopsis: "Sign EIP-712 typed structured data.",
+		HelpDescription: `
+
+    Sign typed structured data conforming to EIP-712, as eth_signTypedData_v4 does. The key-manager
+    must allow the typed_data purpose.
+
+    `,
+		Fields: map[string]*framework.FieldSchema{
+			"name": {Type: framework.TypeString},
+			"address": {
+				Type:        framework.TypeString,
+				Description: "The address that belongs to a private key in the key-manager.",
+			},
+			"typedData": {
+				Type:        framework.TypeMap,
+				Description: "The EIP-712 typed data object with types, primaryType, domain and message.",
+			},
+		},
+	}
+}
+
+func (b *Backend) signTypedData(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	record := newAuditRecord(req, operationSignTypedData)
+	defer emitSigningMetrics(record, time.Now())
+
+	resp, err := b.handleSignTypedData(ctx, req, data, record)
+	b.recordAudit(ctx, req, record, resp, err)
+	return resp, err
+}
+
+func (b *Backend) handleSignTypedData(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+	record *AuditRecord,
+) (*logical.Response, error) {
+	serviceNameInput, ok := data.Get("name").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	address, ok := data.Get("address").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	record.ServiceName = serviceNameInput
+	record.Address = address
+
+	typedDataInput, ok := data.Get("typedData").(map[string]interface{})
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	typedData, err := decodeTypedData(typedDataInput)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, _, err := apitypes.TypedDataAndHash(*typedData)
+	if err != nil {
+		payload, _ := json.Marshal(typedDataInput)
+		b.Logger().Error("Failed to hash the typed data", "typedData", b.redacted(ctx, req, string(payload)),
+			"error", err)
+		return nil, fmt.Errorf("invalid typed data: %w", err)
+	}
+
+	record.Hash = hexutil.Encode(hash)
+
+	keyManager, err := b.retrieveKeyManager(ctx, req, serviceNameInput)
+	if err != nil {
+		b.Logger().Error("Failed to retrieve the signing keyManager",
+			"service_name", serviceNameInput, "error", err)
+		return nil, fmt.Errorf("error retrieving signing keyManager %s", serviceNameInput)
+	}
+
+	if keyManager == nil {
+		return nil, fmt.Errorf("signing keyManager %s does not exist", serviceNameInput)
+	}
+
+	if err = b.checkIdentity(keyManager, req.EntityID); err != nil {
+		return nil, err
+	}
+
+	if err = keyManager.checkPurpose(purposeTypedData); err != nil {
+		return nil, err
+	}
+
+	if err = b.enforceRateLimits(ctx, req, keyManager, address); err != nil {
+		return nil, err
+	}
+
+	_, err = b.enforcePolicies(ctx, req, keyManager, &policyInput{
+		operation:   operationSignTypedData,
+		serviceName: serviceNameInput,
+		address:     address,
+		hash:        hexutil.Encode(hash),
+		typedData:   typedData,
+		entityID:    req.EntityID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keyPair := keyManager.keyPair(address)
+	if keyPair == nil {
+		return nil, errors.New("no private key for the input address")
+	}
+
+	if err = keyManager.checkSigningKey(keyPair); err != nil {
+		return nil, err
+	}
+
+	if err = b.countSignature(ctx, req, keyManager, keyPair); err != nil {
+		return nil, err
+	}
+
+	privateKey, err := keyManager.privateKey(keyPair)
+	if err != nil {
+		b.Logger().Error("Error reconstructing private key from retrieved hex", "error", err)
+		return nil, fmt.Errorf("error reconstructing private key from retrieved hex")
+	}
+	defer zeroKey(privateKey)
+
+	sig, err := crypto.Sign(hash, privateKey)
+	if err != nil {
+		b.Logger().Error("Error signing typed data hash", "error", err)
+		return nil, fmt.Errorf("error signing typed data")
+	}
+	// eth_signTypedData returns the recovery id in the legacy 27/28 form
+	sig[crypto.RecoveryIDOffset] += 27
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"hash":      hexutil.Encode(hash),
+			"signature": common.Bytes2Hex(sig),
+		},
+	}, nil
+}
+
+func decodeTypedData(input map[string]interface{}) (*apitypes.TypedData, error) {
+	raw, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var typedData apitypes.TypedData
+	if err = json.Unmarshal(raw, &typedData); err != nil {
+		return nil, fmt.Errorf("invalid typed data: %w", err)
+	}
+	return &typedData, nil
+}
@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+var testTypedData = map[string]interface{}{
+	"types": map[string]interface{}{
+		"EIP712Domain": []interface{}{
+			map[string]interface{}{"name": "name", "type": "string"},
+			map[string]interface{}{"name": "version", "type": "string"},
+			map[string]interface{}{"name": "chainId", "type": "uint256"},
+			map[string]interface{}{"name": "verifyingContract", "type": "address"},
+		},
+		"Person": []interface{}{
+			map[string]interface{}{"name": "name", "type": "string"},
+			map[string]interface{}{"name": "wallet", "type": "address"},
+		},
+		"Mail": []interface{}{
+			map[string]interface{}{"name": "from", "type": "Person"},
+			map[string]interface{}{"name": "to", "type": "Person"},
+			map[string]interface{}{"name": "contents", "type": "string"},
+		},
+	},
+	"primaryType": "Mail",
+	"domain": map[string]interface{}{
+		"name":              "Ether Mail",
+		"version":           "1",
+		"chainId":           "1",
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC",
+	},
+	"message": map[string]interface{}{
+		"from": map[string]interface{}{
+			"name":   "Cow",
+			"wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826",
+		},
+		"to": map[string]interface{}{
+			"name":   "Bob",
+			"wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB",
+		},
+		"contents": "Hello, Bob!",
+	},
+}
+
+func TestBackend_signTypedData(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const (
+		testSvc          = "test-service"
+		privateKeyString = "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1"
+	)
+	privateKey, err := crypto.HexToECDSA(privateKeyString)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": testSvc,
+		"privateKey":  privateKeyString,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+testSvc+"/typed-data/sign")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"address":   address.String(),
+		"typedData": testTypedData,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// hash of the EIP-712 reference example
+	assert.Equal(t, "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2", resp.Data["hash"])
+
+	sig := common.Hex2Bytes(resp.Data["signature"].(string))
+	assert.Contains(t, []byte{27, 28}, sig[crypto.RecoveryIDOffset])
+	sig[crypto.RecoveryIDOffset] -= 27
+
+	hash, err := hexutil.Decode(resp.Data["hash"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigPublicKey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, address, crypto.PubkeyToAddress(*sigPublicKey))
+}
+
+func TestBackend_signTypedDataFailure1(t *testing.T) {
+	b, _ := newTestBackend(t)
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/my-service/typed-data/sign")
+	req.Data = map[string]interface{}{
+		"typedData": map[string]interface{}{
+			"primaryType": "Mail",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+
+	assert.ErrorContains(t, err, "invalid typed data")
+}
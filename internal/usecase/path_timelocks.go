@@ -0,0 +1,319 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	timeLockStatusLocked    = "locked"
+	timeLockStatusSignable  = "signable"
+	timeLockStatusSigned    = "signed"
+	timeLockStatusCancelled = "cancelled"
+)
+
+// TimeLockedTransaction is a signTx request that may only be signed once its delay has passed.
+type TimeLockedTransaction struct {
+	QueuedTransaction
+	NotBefore   time.Time `json:"not_before"`
+	Status      string    `json:"status"`
+	CancelledBy string    `json:"cancelled_by"`
+	CancelledAt time.Time `json:"cancelled_at"`
+}
+
+func (t *TimeLockedTransaction) currentStatus(now time.Time) string {
+	if t.Status == timeLockStatusLocked && !now.Before(t.NotBefore) {
+		return timeLockStatusSignable
+	}
+	return t.Status
+}
+
+func pathTimeLocks(b *Backend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "timelocks/?",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.listTimeLocks,
+				},
+			},
+			HelpSynopsis: "List the time-locked transactions.",
+		},
+		{
+			Pattern: "timelocks/" + framework.GenericNameRegex("id"),
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.readTimeLock,
+				},
+			},
+			HelpSynopsis: "Fetch a time-locked transaction and its status.",
+			HelpDescription: `
+
+    GET - return the time-locked transaction and its status, with the signed transaction once it has
+    been released.
+
+    `,
+			Fields: map[string]*framework.FieldSchema{
+				"id": {Type: framework.TypeString},
+			},
+		},
+		{
+			Pattern: "timelocks/" + framework.GenericNameRegex("id") + "/release",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.releaseTimeLock,
+				},
+			},
+			HelpSynopsis: "Sign a time-locked transaction once its delay has passed.",
+			Fields: map[string]*framework.FieldSchema{
+				"id": {Type: framework.TypeString},
+			},
+		},
+		{
+			Pattern: "timelocks/" + framework.GenericNameRegex("id") + "/cancel",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.cancelTimeLock,
+				},
+			},
+			HelpSynopsis: "Cancel a time-locked transaction before it is signed.",
+			Fields: map[string]*framework.FieldSchema{
+				"id": {Type: framework.TypeString},
+			},
+		},
+	}
+}
+
+func (b *Backend) queueWithTimeLock(
+	ctx context.Context,
+	req *logical.Request,
+	keyManager *KeyManager,
+	fields *RequestFieldsTransaction,
+) (*logical.Response, error) {
+	if keyManager.SigningDelay == 0 {
+		return nil, fmt.Errorf("transaction requires a delay but keyManager %s has no signingDelay configured",
+			keyManager.ServiceName)
+	}
+
+	queued, err := newQueuedTransaction(req, keyManager, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	timeLock := &TimeLockedTransaction{
+		QueuedTransaction: *queued,
+		NotBefore:         queued.CreatedAt.Add(keyManager.SigningDelay),
+		Status:            timeLockStatusLocked,
+	}
+
+	if err = b.saveTimeLockedTransaction(ctx, req, timeLock); err != nil {
+		return nil, err
+	}
+
+	b.Logger().Info("Transaction time-locked", "service_name", keyManager.ServiceName,
+		"timelock_id", timeLock.ID, "not_before", timeLock.NotBefore)
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"status":     timeLockStatusLocked,
+			"timelockId": timeLock.ID,
+			"notBefore":  timeLock.NotBefore,
+		},
+	}, nil
+}
+
+func (b *Backend) retrieveTimeLockedTransaction(
+	ctx context.Context,
+	req *logical.Request,
+	id string,
+) (*TimeLockedTransaction, error) {
+	path := fmt.Sprintf("timelocks/%s", id)
+	entry, err := req.Storage.Get(ctx, path)
+	if err != nil {
+		b.Logger().Error("Failed to retrieve the time-locked transaction", "path", path, "error", err)
+		return nil, err
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	var timeLock TimeLockedTransaction
+	if err = entry.DecodeJSON(&timeLock); err != nil {
+		b.Logger().Error("Failed to decode time-locked transaction", "path", path, "error", err)
+		return nil, err
+	}
+	return &timeLock, nil
+}
+
+func (b *Backend) saveTimeLockedTransaction(
+	ctx context.Context,
+	req *logical.Request,
+	timeLock *TimeLockedTransaction,
+) error {
+	entry, _ := logical.StorageEntryJSON(fmt.Sprintf("timelocks/%s", timeLock.ID), timeLock)
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		b.Logger().Error("Failed to save the time-locked transaction to storage", "id", timeLock.ID, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (b *Backend) listTimeLocks(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	ids, err := req.Storage.List(ctx, "timelocks/")
+	if err != nil {
+		b.Logger().Error("Failed to retrieve the list of time-locked transactions", "error", err)
+		return nil, err
+	}
+
+	now := time.Now()
+	keyInfo := make(map[string]interface{}, len(ids))
+	for _, id := range ids {
+		timeLock, err := b.retrieveTimeLockedTransaction(ctx, req, id)
+		if err != nil {
+			return nil, err
+		}
+		if timeLock == nil {
+			continue
+		}
+		keyInfo[id] = map[string]interface{}{
+			"service_name": timeLock.ServiceName,
+			"address":      timeLock.Address,
+			"status":       timeLock.currentStatus(now),
+			"not_before":   timeLock.NotBefore,
+		}
+	}
+
+	return logical.ListResponseWithInfo(ids, keyInfo), nil
+}
+
+func (b *Backend) readTimeLock(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	id, ok := data.Get("id").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	timeLock, err := b.retrieveTimeLockedTransaction(ctx, req, id)
+	if err != nil {
+		return nil, err
+	}
+	if timeLock == nil {
+		return nil, fmt.Errorf("time-locked transaction does not exist")
+	}
+
+	return timeLockResponse(timeLock), nil
+}
+
+func (b *Backend) releaseTimeLock(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	id, ok := data.Get("id").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	b.queueLock.Lock()
+	defer b.queueLock.Unlock()
+
+	timeLock, err := b.retrieveTimeLockedTransaction(ctx, req, id)
+	if err != nil {
+		return nil, err
+	}
+	if timeLock == nil {
+		return nil, fmt.Errorf("time-locked transaction does not exist")
+	}
+
+	if status := timeLock.currentStatus(time.Now()); status != timeLockStatusSignable {
+		if status == timeLockStatusLocked {
+			return nil, fmt.Errorf("time-locked transaction %s cannot be signed before %s", id,
+				timeLock.NotBefore.Format(time.RFC3339))
+		}
+		return nil, fmt.Errorf("time-locked transaction %s is already %s", id, status)
+	}
+
+	keyManager, err := b.retrieveKeyManager(ctx, req, timeLock.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	if keyManager != nil {
+		if err = b.checkIdentity(keyManager, req.EntityID); err != nil {
+			return nil, err
+		}
+	}
+
+	if timeLock.Result, err = b.signQueuedTransaction(ctx, req, &timeLock.QueuedTransaction,
+		fmt.Sprintf("released by timelock %s", id)); err != nil {
+		return nil, err
+	}
+	timeLock.Status = timeLockStatusSigned
+	if err = b.saveTimeLockedTransaction(ctx, req, timeLock); err != nil {
+		return nil, err
+	}
+	b.Logger().Info("Time-locked transaction signed", "service_name", timeLock.ServiceName, "timelock_id", id)
+
+	return timeLockResponse(timeLock), nil
+}
+
+func (b *Backend) cancelTimeLock(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	id, ok := data.Get("id").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	b.queueLock.Lock()
+	defer b.queueLock.Unlock()
+
+	timeLock, err := b.retrieveTimeLockedTransaction(ctx, req, id)
+	if err != nil {
+		return nil, err
+	}
+	if timeLock == nil {
+		return nil, fmt.Errorf("time-locked transaction does not exist")
+	}
+
+	if timeLock.Status != timeLockStatusLocked {
+		return nil, fmt.Errorf("time-locked transaction %s is already %s", id, timeLock.Status)
+	}
+
+	timeLock.Status = timeLockStatusCancelled
+	timeLock.CancelledBy = req.EntityID
+	timeLock.CancelledAt = time.Now().UTC()
+
+	if err = b.saveTimeLockedTransaction(ctx, req, timeLock); err != nil {
+		return nil, err
+	}
+
+	b.Logger().Info("Time-locked transaction cancelled", "service_name", timeLock.ServiceName,
+		"timelock_id", id, "cancelled_by", timeLock.CancelledBy)
+
+	return timeLockResponse(timeLock), nil
+}
+
+func timeLockResponse(timeLock *TimeLockedTransaction) *logical.Response {
+	resp := timeLock.response()
+	resp.Data["status"] = timeLock.currentStatus(time.Now())
+	resp.Data["not_before"] = timeLock.NotBefore
+	if timeLock.Status == timeLockStatusCancelled {
+		resp.Data["cancelled_by"] = timeLock.CancelledBy
+		resp.Data["cancelled_at"] = timeLock.CancelledAt
+	}
+	return resp
+}
@@ -0,0 +1,243 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackend_timeLocks(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const (
+		treasurySvc = "treasury-service"
+		address     = "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704"
+		privateKey  = "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1"
+	)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": treasurySvc,
+		"privateKey":  privateKey,
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "policies/withdrawals")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{
+				"name":       "withdrawal",
+				"expression": `tx.to != "0xf809410b0d6f047c603deb311979cd413e025a84"`,
+				"effect":     "delay",
+			},
+			map[string]interface{}{
+				"name":       "default",
+				"expression": `true`,
+				"effect":     "allow",
+			},
+		},
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+treasurySvc+"/config")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"policies":     "withdrawals",
+		"signingDelay": 1,
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	signTx := func(nonce string) *logical.Response {
+		req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+treasurySvc+"/txn/sign")
+		req.Storage = storage
+		req.Data = map[string]interface{}{
+			"address":  address,
+			"data":     "0x",
+			"to":       "0xaa1fd73c4981aeb9d051eff70b055eb50ba94c32",
+			"value":    "1000",
+			"gas":      21000,
+			"nonce":    nonce,
+			"gasPrice": 1,
+			"chainId":  "1",
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return resp
+	}
+
+	fetch := func(id string) *logical.Response {
+		req := logical.TestRequest(t, logical.ReadOperation, "timelocks/"+id)
+		req.Storage = storage
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return resp
+	}
+
+	resp := signTx("0x1")
+	assert.Equal(t, "locked", resp.Data["status"])
+	signedID := resp.Data["timelockId"].(string)
+
+	resp = signTx("0x2")
+	cancelledID := resp.Data["timelockId"].(string)
+
+	release := func(id string) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "timelocks/"+id+"/release")
+		req.Storage = storage
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	resp = fetch(signedID)
+	assert.Equal(t, "locked", resp.Data["status"])
+	assert.Nil(t, resp.Data["signedTx"])
+
+	_, err = release(signedID)
+	assert.ErrorContains(t, err, "time-locked transaction "+signedID+" cannot be signed before")
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "timelocks/"+cancelledID+"/cancel")
+	req.Storage = storage
+	req.EntityID = "security-team"
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, "cancelled", resp.Data["status"])
+	assert.Equal(t, "security-team", resp.Data["cancelled_by"])
+
+	time.Sleep(1100 * time.Millisecond)
+
+	// reading a signable transaction does not sign it
+	resp = fetch(signedID)
+	assert.Equal(t, "signable", resp.Data["status"])
+	assert.Nil(t, resp.Data["signedTx"])
+
+	resp, err = release(signedID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, "signed", resp.Data["status"])
+	assert.NotEmpty(t, resp.Data["signedTx"])
+
+	resp = fetch(signedID)
+	assert.Equal(t, "signed", resp.Data["status"])
+	assert.Equal(t, resp.Data["signedTx"], fetch(signedID).Data["signedTx"])
+
+	_, err = release(signedID)
+	assert.EqualError(t, err, "time-locked transaction "+signedID+" is already signed")
+
+	resp = fetch(cancelledID)
+	assert.Equal(t, "cancelled", resp.Data["status"])
+	assert.Nil(t, resp.Data["signedTx"])
+
+	_, err = release(cancelledID)
+	assert.EqualError(t, err, "time-locked transaction "+cancelledID+" is already cancelled")
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "timelocks/"+signedID+"/cancel")
+	req.Storage = storage
+	_, err = b.HandleRequest(context.Background(), req)
+	assert.EqualError(t, err, "time-locked transaction "+signedID+" is already signed")
+}
+
+func TestBackend_timeLocksReleaseChecks(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	const (
+		treasurySvc = "treasury-service"
+		address     = "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704"
+	)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers")
+	storage := req.Storage
+	req.Data = map[string]interface{}{
+		"serviceName": treasurySvc,
+		"privateKey":  "3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	update := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, path)
+		req.Storage = storage
+		req.Data = data
+		return b.HandleRequest(context.Background(), req)
+	}
+	writePolicy := func(effect string) {
+		_, err := update("policies/withdrawals", map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{"name": "withdrawal", "expression": `true`, "effect": effect},
+			},
+		})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	writePolicy("delay")
+	if _, err = update("key-managers/"+treasurySvc+"/config", map[string]interface{}{
+		"policies":     "withdrawals",
+		"signingDelay": 1,
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp, err := update("key-managers/"+treasurySvc+"/txn/sign", map[string]interface{}{
+		"address":  address,
+		"data":     "0x",
+		"to":       "0xaa1fd73c4981aeb9d051eff70b055eb50ba94c32",
+		"value":    "1000",
+		"gas":      21000,
+		"nonce":    "0x1",
+		"gasPrice": 1,
+		"chainId":  "1",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	id := resp.Data["timelockId"].(string)
+
+	time.Sleep(1100 * time.Millisecond)
+
+	// the transaction is checked against the settings of the key-manager when it is released
+	if _, err = update("key-managers/"+treasurySvc+"/config", map[string]interface{}{
+		"allowedChainIds": "137",
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, err = update("timelocks/"+id+"/release", nil)
+	assert.EqualError(t, err, "chainId 1 is not allowed for keyManager "+treasurySvc)
+
+	if _, err = update("key-managers/"+treasurySvc+"/config", map[string]interface{}{
+		"allowedChainIds": "1,137",
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	writePolicy("deny")
+	_, err = update("timelocks/"+id+"/release", nil)
+	assert.ErrorContains(t, err, "request denied by policy withdrawals")
+
+	writePolicy("delay")
+	resp, err = update("timelocks/"+id+"/release", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, "signed", resp.Data["status"])
+}
@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathWrappedImport(b *Backend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "wrapping_key",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.readWrappingKey,
+				},
+			},
+			HelpSynopsis: "Return the public key that private keys are encrypted to before they are imported.",
+			HelpDescription: `
+
+    GET - return the PEM encoded RSA public key of the mount. Encrypt a 32-byte private key to it with
+    RSA-OAEP (SHA-256) and pass the base64 ciphertext to key-managers/<name>/import.
+
+    `,
+		},
+		{
+			Pattern: "key-managers/" + framework.GenericNameRegex("name") + "/import",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.importWrappedKey,
+				},
+			},
+			HelpSynopsis: "Import a private key encrypted to the wrapping key of the mount.",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {Type: framework.TypeString},
+				"ciphertext": {
+					Type:        framework.TypeString,
+					Description: "The base64 encoded RSA-OAEP (SHA-256) encryption of the 32-byte private key to the wrapping key.",
+				},
+			},
+		},
+	}
+}
+
+func (b *Backend) readWrappingKey(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	wrappingKey, err := b.retrieveWrappingKey(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := encodePublicKeyPEM(&wrappingKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"public_key": publicKey,
+		},
+	}, nil
+}
+
+func (b *Backend) importWrappedKey(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	serviceName, ok := data.Get("name").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	ciphertext, ok := data.Get("ciphertext").(string)
+	if !ok {
+		return nil, errInvalidType
+	}
+
+	plaintext, err := b.unwrap(ctx, req, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(plaintext)
+
+	privateKey, err := crypto.ToECDSA(plaintext)
+	if err != nil {
+		b.Logger().Error("Error reconstructing the unwrapped private key",
+			"privateKey", redactedValue, "error", err)
+		return nil, fmt.Errorf("error reconstructing the unwrapped private key, %w", err)
+	}
+	defer zeroKey(privateKey)
+
+	return b.importKeyPair(ctx, req, serviceName, privateKey)
+}
@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackend_importWrappedKey(t *testing.T) {
+	defer func(bits int) { wrappingKeyBits = bits }(wrappingKeyBits)
+	wrappingKeyBits = 2048
+
+	b, _ := newTestBackend(t)
+
+	const coldSvc = "cold-storage"
+
+	req := logical.TestRequest(t, logical.ReadOperation, "wrapping_key")
+	storage := req.Storage
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	publicKeyPEM := resp.Data["public_key"].(string)
+
+	// the wrapping key is generated once per mount
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, publicKeyPEM, resp.Data["public_key"])
+
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	ciphertext, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, parsed.(*rsa.PublicKey),
+		common.FromHex("3ee65159f7aa057c482b1041f18f37ce90ef5e460cb46fd3fa0c40fbae41c7e1"), nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+coldSvc+"/import")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"ciphertext": base64.StdEncoding.EncodeToString(ciphertext),
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Equal(t, "0xBffc2f3Df75367B0f246aF6Ae42AFf59A33f2704", resp.Data["address"])
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "key-managers/"+coldSvc+"/import")
+	req.Storage = storage
+	req.Data = map[string]interface{}{
+		"ciphertext": base64.StdEncoding.EncodeToString([]byte("not encrypted to the wrapping key")),
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	assert.EqualError(t, err, "failed to unwrap the ciphertext with the wrapping key")
+}
+
+func TestBackend_importWrappedKeyFailure1(t *testing.T) {
+	b, _ := newTestBackend(t)
+	req := logical.TestRequest(t, logical.UpdateOperation, "key-managers/cold-storage/import")
+	req.Data = map[string]interface{}{
+		"ciphertext": "%%%",
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+
+	assert.EqualError(t, err, "ciphertext must be base64 encoded")
+}
@@ -0,0 +1,391 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/google/cel-go/cel"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	policyEffectAllow           = "allow"
+	policyEffectDeny            = "deny"
+	policyEffectRequireApproval = "require_approval"
+	policyEffectDelay           = "delay"
+)
+
+// queuedEffects ranks the effects that queue a signTx request instead of signing it right away.
+var queuedEffects = map[string]int{
+	policyEffectAllow:           0,
+	policyEffectDelay:           1,
+	policyEffectRequireApproval: 2,
+}
+
+// Policy is an ordered list of rules. The first rule whose expression evaluates to true decides
+// the outcome, and a request that matches no rule is denied.
+type Policy struct {
+	Name  string        `json:"name"`
+	Rules []*PolicyRule `json:"rules"`
+}
+
+// PolicyRule is a CEL expression over the request variables and the effect applied when it matches.
+type PolicyRule struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+	Effect     string `json:"effect"`
+}
+
+// PolicyDecision explains the outcome of evaluating a policy.
+type PolicyDecision struct {
+	Policy      string        `json:"policy"`
+	Effect      string        `json:"effect"`
+	MatchedRule string        `json:"matched_rule"`
+	Rules       []*RuleResult `json:"rules"`
+}
+
+// RuleResult is the outcome of a single rule.
+type RuleResult struct {
+	Name    string `json:"name"`
+	Matched bool   `json:"matched"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Allowed reports whether the decision permits the request.
+func (d *PolicyDecision) Allowed() bool {
+	return d.Effect == policyEffectAllow
+}
+
+// policyInput is the signing request as seen by the policy expressions.
+type policyInput struct {
+	operation   string
+	serviceName string
+	address     string
+	tx          *types.Transaction
+	chainID     *big.Int
+	hash        string
+	typedData   *apitypes.TypedData
+	entityID    string
+	time        time.Time
+}
+
+func newPolicyEnv() (*cel.Env, error) {
+	anyMap := cel.MapType(cel.StringType, cel.DynType)
+	return cel.NewEnv(
+		cel.Variable("request", anyMap),
+		cel.Variable("tx", anyMap),
+		cel.Variable("typedData", anyMap),
+		cel.Variable("hash", cel.StringType),
+		cel.Variable("identity", anyMap),
+		cel.CrossTypeNumericComparisons(true),
+	)
+}
+
+func (b *Backend) policyEnv() (*cel.Env, error) {
+	b.policyEnvOnce.Do(func() {
+		b.celEnv, b.celEnvErr = newPolicyEnv()
+	})
+	return b.celEnv, b.celEnvErr
+}
+
+// compiledRule is the program of a rule expression, or the error compiling it.
+type compiledRule struct {
+	expression string
+	program    cel.Program
+	err        error
+}
+
+// policyCache holds the compiled rules of the policies by name, so that signing requests do not
+// recompile them. A rule is recompiled when its expression changed.
+type policyCache struct {
+	lock     sync.Mutex
+	policies map[string][]*compiledRule
+}
+
+// programs returns the compiled rules of the policy, in the order of its rules.
+func (b *Backend) programs(policy *Policy) []*compiledRule {
+	b.policyCache.lock.Lock()
+	defer b.policyCache.lock.Unlock()
+
+	if b.policyCache.policies == nil {
+		b.policyCache.policies = make(map[string][]*compiledRule)
+	}
+
+	cached := b.policyCache.policies[policy.Name]
+	compiled := make([]*compiledRule, len(policy.Rules))
+	for i, rule := range policy.Rules {
+		if i < len(cached) && cached[i].expression == rule.Expression {
+			compiled[i] = cached[i]
+			continue
+		}
+		program, err := b.compile(rule)
+		compiled[i] = &compiledRule{expression: rule.Expression, program: program, err: err}
+	}
+	b.policyCache.policies[policy.Name] = compiled
+	return compiled
+}
+
+// forgetPolicy drops the compiled rules of a policy that was replaced or deleted.
+func (b *Backend) forgetPolicy(name string) {
+	b.policyCache.lock.Lock()
+	defer b.policyCache.lock.Unlock()
+	delete(b.policyCache.policies, name)
+}
+
+// compile type-checks the rule expression, which must evaluate to a bool.
+func (b *Backend) compile(rule *PolicyRule) (cel.Program, error) {
+	env, err := b.policyEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(rule.Expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("rule %q: %w", rule.Name, issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("rule %q: expression must evaluate to a bool, got %s", rule.Name, ast.OutputType())
+	}
+	return env.Program(ast)
+}
+
+func (b *Backend) validatePolicy(policy *Policy) error {
+	if len(policy.Rules) == 0 {
+		return fmt.Errorf("policy %s must have at least one rule", policy.Name)
+	}
+	for i, rule := range policy.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i)
+		}
+		switch rule.Effect {
+		case policyEffectAllow, policyEffectDeny, policyEffectRequireApproval, policyEffectDelay:
+		default:
+			return fmt.Errorf("rule %q: invalid effect %q, must be %q, %q, %q or %q", rule.Name, rule.Effect,
+				policyEffectAllow, policyEffectDeny, policyEffectRequireApproval, policyEffectDelay)
+		}
+		if _, err := b.compile(rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evaluatePolicy runs the rules in order. Evaluation errors fail closed and deny the request.
+func (b *Backend) evaluatePolicy(policy *Policy, activation map[string]interface{}) *PolicyDecision {
+	decision := &PolicyDecision{
+		Policy: policy.Name,
+		Effect: policyEffectDeny,
+	}
+
+	programs := b.programs(policy)
+	for i, rule := range policy.Rules {
+		result := &RuleResult{Name: rule.Name}
+		decision.Rules = append(decision.Rules, result)
+
+		if programs[i].err != nil {
+			result.Error = programs[i].err.Error()
+			return decision
+		}
+
+		out, _, err := programs[i].program.Eval(activation)
+		if err != nil {
+			result.Error = err.Error()
+			return decision
+		}
+
+		matched, ok := out.Value().(bool)
+		if !ok {
+			result.Error = "expression did not evaluate to a bool"
+			return decision
+		}
+
+		if matched {
+			result.Matched = true
+			decision.Effect = rule.Effect
+			decision.MatchedRule = rule.Name
+			return decision
+		}
+	}
+	return decision
+}
+
+// enforcePolicies evaluates every policy attached to the key-manager and rejects the request
+// unless all of them allow it. For signTx requests that no policy denies, it returns the strongest
+// queueing effect: policyEffectRequireApproval over policyEffectDelay over policyEffectAllow.
+func (b *Backend) enforcePolicies(
+	ctx context.Context,
+	req *logical.Request,
+	keyManager *KeyManager,
+	input *policyInput,
+) (string, error) {
+	if len(keyManager.Policies) == 0 {
+		return policyEffectAllow, nil
+	}
+
+	activation, err := b.policyActivation(input)
+	if err != nil {
+		return "", err
+	}
+
+	effect := policyEffectAllow
+	for _, name := range keyManager.Policies {
+		policy, err := b.retrievePolicy(ctx, req, name)
+		if err != nil {
+			return "", err
+		}
+		if policy == nil {
+			return "", fmt.Errorf("policy %s attached to keyManager %s does not exist", name, keyManager.ServiceName)
+		}
+
+		decision := b.evaluatePolicy(policy, activation)
+		rank, queued := queuedEffects[decision.Effect]
+		switch {
+		case queued && input.operation == operationSignTx:
+			if rank > queuedEffects[effect] {
+				effect = decision.Effect
+			}
+		case !decision.Allowed():
+			b.Logger().Warn("Signing request rejected by policy", "service_name", keyManager.ServiceName,
+				"policy", name, "rule", decision.MatchedRule, "operation", input.operation)
+			emitPolicyRejection(input.operation, keyManager.ServiceName, name)
+			return "", fmt.Errorf("request denied by policy %s", decisionReason(decision))
+		}
+	}
+	return effect, nil
+}
+
+func decisionReason(decision *PolicyDecision) string {
+	if decision.MatchedRule != "" {
+		return fmt.Sprintf("%s (rule %s)", decision.Policy, decision.MatchedRule)
+	}
+	if n := len(decision.Rules); n > 0 && decision.Rules[n-1].Error != "" {
+		return fmt.Sprintf("%s (rule %s failed: %s)", decision.Policy, decision.Rules[n-1].Name, decision.Rules[n-1].Error)
+	}
+	return fmt.Sprintf("%s (no rule matched)", decision.Policy)
+}
+
+// policyActivation builds the variables the policy expressions are evaluated against.
+func (b *Backend) policyActivation(input *policyInput) (map[string]interface{}, error) {
+	requestTime := input.time
+	if requestTime.IsZero() {
+		requestTime = time.Now()
+	}
+
+	identity, err := b.identityVariables(input.entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	activation := map[string]interface{}{
+		"request": map[string]interface{}{
+			"operation":   input.operation,
+			"serviceName": input.serviceName,
+			"address":     strings.ToLower(input.address),
+			"time":        requestTime.UTC(),
+		},
+		"tx":        map[string]interface{}{},
+		"typedData": map[string]interface{}{},
+		"hash":      input.hash,
+		"identity":  identity,
+	}
+
+	if input.tx != nil {
+		activation["tx"] = txVariables(input.tx, input.chainID)
+	}
+
+	if input.typedData != nil {
+		raw, err := json.Marshal(input.typedData)
+		if err != nil {
+			return nil, err
+		}
+		var typedData map[string]interface{}
+		if err = json.Unmarshal(raw, &typedData); err != nil {
+			return nil, err
+		}
+		activation["typedData"] = typedData
+	}
+
+	return activation, nil
+}
+
+func txVariables(tx *types.Transaction, chainID *big.Int) map[string]interface{} {
+	to := ""
+	if tx.To() != nil {
+		to = strings.ToLower(tx.To().Hex())
+	}
+
+	selector := ""
+	if len(tx.Data()) >= 4 {
+		selector = hexutil.Encode(tx.Data()[:4])
+	}
+
+	return map[string]interface{}{
+		"type":      int64(tx.Type()),
+		"to":        to,
+		"value":     weiToFloat(tx.Value()),
+		"valueWei":  tx.Value().String(),
+		"nonce":     int64(tx.Nonce()),
+		"gas":       int64(tx.Gas()),
+		"gasPrice":  weiToFloat(tx.GasPrice()),
+		"gasFeeCap": weiToFloat(tx.GasFeeCap()),
+		"gasTipCap": weiToFloat(tx.GasTipCap()),
+		"chainId":   chainID.Int64(),
+		"data":      hexutil.Encode(tx.Data()),
+		"selector":  selector,
+	}
+}
+
+func weiToFloat(wei *big.Int) float64 {
+	if wei == nil {
+		return 0
+	}
+	f, _ := new(big.Float).SetInt(wei).Float64()
+	return f
+}
+
+// identityVariables looks up the entity the request is made by. A failed lookup fails the
+// evaluation, rather than evaluating identity rules against a partial identity.
+func (b *Backend) identityVariables(entityID string) (map[string]interface{}, error) {
+	identity := map[string]interface{}{
+		"entityId": entityID,
+		"name":     "",
+		"metadata": map[string]string{},
+		"groups":   []string{},
+	}
+	if entityID == "" || b.System() == nil {
+		return identity, nil
+	}
+
+	entity, err := b.System().EntityInfo(entityID)
+	if err != nil {
+		b.Logger().Error("Failed to look up the requesting entity", "entity_id", entityID, "error", err)
+		return nil, fmt.Errorf("failed to look up entity %q: %w", entityID, err)
+	}
+	if entity != nil {
+		identity["name"] = entity.Name
+		if entity.Metadata != nil {
+			identity["metadata"] = entity.Metadata
+		}
+	}
+
+	groups, err := b.System().GroupsForEntity(entityID)
+	if err != nil {
+		b.Logger().Error("Failed to look up the groups of the requesting entity", "entity_id", entityID, "error", err)
+		return nil, fmt.Errorf("failed to look up the groups of entity %q: %w", entityID, err)
+	}
+	names := make([]string, 0, len(groups))
+	for _, group := range groups {
+		names = append(names, group.Name)
+	}
+	identity["groups"] = names
+
+	return identity, nil
+}
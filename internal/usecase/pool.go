@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// poolIndexPath lists the key-managers that keep a pool of pre-generated keys
+	poolIndexPath = "pools/"
+
+	keyStatusPooled = "pooled"
+)
+
+// pooled returns the number of unclaimed keys in the pool of the key-manager.
+func (k *KeyManager) pooled() int {
+	var n int
+	for _, keyPair := range k.KeyPairs {
+		if keyPair.Pooled {
+			n++
+		}
+	}
+	return n
+}
+
+// fillPool generates keys until the pool holds PoolSize unclaimed keys and reports whether it
+// added any.
+func (k *KeyManager) fillPool() (bool, error) {
+	missing := k.PoolSize - k.pooled()
+	for i := 0; i < missing; i++ {
+		keyPair, err := k.generateKeyPair()
+		if err != nil {
+			return i > 0, err
+		}
+		keyPair.Pooled = true
+		k.KeyPairs = append(k.KeyPairs, keyPair)
+	}
+	return missing > 0, nil
+}
+
+// claimKeyPair takes the oldest key out of the pool of the key-manager, or generates a key when
+// the pool is empty, and makes it usable for signing.
+func (b *Backend) claimKeyPair(ctx context.Context, req *logical.Request, serviceName string) (*KeyPair, error) {
+	b.keyManagerLock.Lock()
+	defer b.keyManagerLock.Unlock()
+
+	keyManager, err := b.retrieveKeyManager(ctx, req, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if keyManager == nil {
+		return nil, fmt.Errorf("keyManager does not exist")
+	}
+
+	if err = b.checkIdentity(keyManager, req.EntityID); err != nil {
+		return nil, err
+	}
+
+	var claimed *KeyPair
+	for _, keyPair := range keyManager.KeyPairs {
+		if keyPair.Pooled {
+			claimed = keyPair
+			break
+		}
+	}
+	if claimed == nil {
+		b.Logger().Warn("Key pool is empty, generating the claimed key", "service_name", serviceName)
+		if claimed, err = keyManager.generateKeyPair(); err != nil {
+			return nil, err
+		}
+		keyManager.KeyPairs = append(keyManager.KeyPairs, claimed)
+	}
+	claimed.Pooled = false
+
+	if err = b.saveKeyManager(ctx, req, keyManager); err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// refillPool tops up the pool of the key-manager.
+func (b *Backend) refillPool(ctx context.Context, storage logical.Storage, serviceName string) error {
+	b.keyManagerLock.Lock()
+	defer b.keyManagerLock.Unlock()
+
+	req := &logical.Request{Storage: storage}
+	keyManager, err := b.retrieveKeyManager(ctx, req, serviceName)
+	if err != nil {
+		return err
+	}
+	if keyManager == nil || keyManager.PoolSize == 0 {
+		return storage.Delete(ctx, poolIndexPath+serviceName)
+	}
+
+	added, err := keyManager.fillPool()
+	if !added {
+		return err
+	}
+	if saveErr := b.saveKeyManager(ctx, req, keyManager); saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+// refillPools tops up the pools of every key-manager that keeps one.
+func (b *Backend) refillPools(ctx context.Context, storage logical.Storage) error {
+	names, err := storage.List(ctx, poolIndexPath)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err = b.refillPool(ctx, storage, name); err != nil {
+			b.Logger().Error("Failed to refill the key pool", "service_name", name, "error", err)
+		}
+	}
+	return nil
+}
+
+// savePoolIndex adds the key-manager to the pool index when it keeps a pool, and removes it
+// otherwise.
+func savePoolIndex(ctx context.Context, storage logical.Storage, keyManager *KeyManager) error {
+	if keyManager.PoolSize == 0 {
+		return storage.Delete(ctx, poolIndexPath+keyManager.ServiceName)
+	}
+	return storage.Put(ctx, &logical.StorageEntry{Key: poolIndexPath + keyManager.ServiceName})
+}
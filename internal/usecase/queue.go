@@ -0,0 +1,174 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// QueuedTransaction is an unsigned signTx request persisted until it may be signed.
+type QueuedTransaction struct {
+	ID           string             `json:"id"`
+	ServiceName  string             `json:"service_name"`
+	Address      string             `json:"address"`
+	ChainID      string             `json:"chain_id"`
+	Tx           string             `json:"tx"`
+	Replacement  bool               `json:"replacement,omitempty"`
+	DerivationID string             `json:"derivation_id,omitempty"`
+	RequestedBy  string             `json:"requested_by"`
+	CreatedAt    time.Time          `json:"created_at"`
+	Result       *SignedTransaction `json:"result"`
+}
+
+func newQueuedTransaction(
+	req *logical.Request,
+	keyManager *KeyManager,
+	fields *RequestFieldsTransaction,
+) (*QueuedTransaction, error) {
+	rawTx, err := fields.tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueuedTransaction{
+		ID:           id,
+		ServiceName:  keyManager.ServiceName,
+		Address:      fields.address,
+		ChainID:      fields.chainID.String(),
+		Tx:           hexutil.Encode(rawTx),
+		Replacement:  fields.replacement,
+		DerivationID: fields.derivationID,
+		RequestedBy:  req.EntityID,
+		CreatedAt:    time.Now().UTC(),
+	}, nil
+}
+
+// signQueuedTransaction signs the queued transaction with the key it was requested for and
+// records the release in the audit log. The key-manager may have changed while the transaction
+// waited, so its checks run again against the current settings.
+func (b *Backend) signQueuedTransaction(
+	ctx context.Context,
+	req *logical.Request,
+	queued *QueuedTransaction,
+	reason string,
+) (*SignedTransaction, error) {
+	record := newAuditRecord(req, operationSignTx)
+	record.ServiceName = queued.ServiceName
+	record.Address = queued.Address
+	record.ChainID = queued.ChainID
+	record.Reason = reason
+	defer emitSigningMetrics(record, time.Now())
+
+	signed, err := b.releaseQueuedTransaction(ctx, req, queued, record)
+
+	var resp *logical.Response
+	if signed != nil {
+		resp = &logical.Response{Data: signed.responseData()}
+	}
+	b.recordAudit(ctx, req, record, resp, err)
+	return signed, err
+}
+
+func (b *Backend) releaseQueuedTransaction(
+	ctx context.Context,
+	req *logical.Request,
+	queued *QueuedTransaction,
+	record *AuditRecord,
+) (*SignedTransaction, error) {
+	keyManager, err := b.retrieveKeyManager(ctx, req, queued.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	if keyManager == nil {
+		return nil, fmt.Errorf("signing keyManager %s does not exist", queued.ServiceName)
+	}
+
+	// the requesting entity may have lost its identity binding while the transaction waited
+	if err = b.checkIdentity(keyManager, queued.RequestedBy); err != nil {
+		return nil, err
+	}
+
+	keyPair, err := keyManager.resolveKeyPair(queued.Address, queued.DerivationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the key may have been retired while the transaction waited
+	if err = keyManager.checkSigningKey(keyPair); err != nil {
+		return nil, err
+	}
+
+	rawTx, err := hexutil.Decode(queued.Tx)
+	if err != nil {
+		return nil, err
+	}
+	tx := new(types.Transaction)
+	if err = tx.UnmarshalBinary(rawTx); err != nil {
+		return nil, err
+	}
+
+	chainID, ok := new(big.Int).SetString(queued.ChainID, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid chainId %s", queued.ChainID)
+	}
+
+	record.setTx(tx, chainID)
+
+	config, err := b.retrieveConfig(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkChainID(config, keyManager, chainID); err != nil {
+		return nil, err
+	}
+
+	// the transaction already went through approval or its delay, only a deny stops it now
+	if _, err = b.enforcePolicies(ctx, req, keyManager, &policyInput{
+		operation:   operationSignTx,
+		serviceName: keyManager.ServiceName,
+		address:     keyPair.Address,
+		tx:          tx,
+		chainID:     chainID,
+		entityID:    queued.RequestedBy,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err = b.enforceRateLimits(ctx, req, keyManager, keyPair.Address); err != nil {
+		return nil, err
+	}
+
+	signed, _, err := b.signAndRecordTransaction(ctx, req, keyManager, keyPair, tx, chainID, queued.Replacement)
+	return signed, err
+}
+
+func (q *QueuedTransaction) response() *logical.Response {
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"id":           q.ID,
+			"service_name": q.ServiceName,
+			"address":      q.Address,
+			"chain_id":     q.ChainID,
+			"tx":           q.Tx,
+			"requested_by": q.RequestedBy,
+			"created_at":   q.CreatedAt,
+		},
+	}
+	if q.Result != nil {
+		for k, v := range q.Result.responseData() {
+			resp.Data[k] = v
+		}
+	}
+	return resp
+}
@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/time/rate"
+)
+
+// RateLimit is a token bucket of Rate signing requests per second, up to Burst at once.
+type RateLimit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// parseRateLimit reads the rate and burst fields into a rate limit. A rate of 0 disables the limit,
+// and the burst defaults to the rate rounded up.
+func parseRateLimit(data *framework.FieldData, current *RateLimit, rateField, burstField string) (*RateLimit, error) {
+	limit := &RateLimit{}
+	if current != nil {
+		*limit = *current
+	}
+
+	rawRate, rateOk := data.GetOk(rateField)
+	rawBurst, burstOk := data.GetOk(burstField)
+	if !rateOk && !burstOk {
+		return current, nil
+	}
+	if rateOk {
+		limit.Rate = rawRate.(float64)
+		if !burstOk {
+			limit.Burst = 0
+		}
+	}
+	if burstOk {
+		limit.Burst = rawBurst.(int)
+	}
+
+	if limit.Rate < 0 || limit.Burst < 0 {
+		return nil, fmt.Errorf("%s and %s cannot be negative", rateField, burstField)
+	}
+	if limit.Rate == 0 {
+		return nil, nil
+	}
+	if limit.Burst == 0 {
+		limit.Burst = int(math.Ceil(limit.Rate))
+	}
+	return limit, nil
+}
+
+func (l *RateLimit) response() map[string]interface{} {
+	if l == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"rate":  l.Rate,
+		"burst": l.Burst,
+	}
+}
+
+// enforceRateLimits takes a token from the buckets of the requesting entity, the key-manager and
+// the address, rejecting the request as soon as one of them is empty.
+func (b *Backend) enforceRateLimits(
+	ctx context.Context,
+	req *logical.Request,
+	keyManager *KeyManager,
+	address string,
+) error {
+	config, err := b.retrieveConfig(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if req.EntityID != "" && !b.allow("entity/"+req.EntityID, config.EntityRateLimit) {
+		return b.rateLimited(keyManager, fmt.Sprintf("signing rate of entity %q exceeded", req.EntityID))
+	}
+
+	if !b.allow("key-manager/"+keyManager.ServiceName, keyManager.RateLimit) {
+		return b.rateLimited(keyManager, fmt.Sprintf("signing rate of keyManager %s exceeded", keyManager.ServiceName))
+	}
+
+	addressKey := "address/" + keyManager.ServiceName + "/" + strings.ToLower(address)
+	if address != "" && !b.allow(addressKey, keyManager.AddressRateLimit) {
+		return b.rateLimited(keyManager, fmt.Sprintf("signing rate of address %s exceeded", address))
+	}
+	return nil
+}
+
+// rateLimited returns a 429 error. A coded error keeps its status code across the plugin gRPC
+// boundary, which only preserves the exact logical sentinel errors.
+func (b *Backend) rateLimited(keyManager *KeyManager, reason string) error {
+	err := logical.CodedError(http.StatusTooManyRequests,
+		fmt.Sprintf("%s: %s", logical.ErrRateLimitQuotaExceeded, reason))
+	b.Logger().Warn("Signing request rate limited", "service_name", keyManager.ServiceName, "error", err)
+	return err
+}
+
+// isRateLimited reports whether the request was rejected by a rate limit.
+func isRateLimited(err error) bool {
+	var coded logical.HTTPCodedError
+	return errors.As(err, &coded) && coded.Code() == http.StatusTooManyRequests
+}
+
+// allow takes a token from the bucket of the key, which follows the current limit.
+func (b *Backend) allow(key string, limit *RateLimit) bool {
+	if limit == nil {
+		return true
+	}
+
+	b.limitersLock.Lock()
+	defer b.limitersLock.Unlock()
+
+	if b.limiters == nil {
+		b.limiters = make(map[string]*rate.Limiter)
+	}
+
+	limiter, ok := b.limiters[key]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)
+		b.limiters[key] = limiter
+	} else if limiter.Limit() != rate.Limit(limit.Rate) || limiter.Burst() != limit.Burst {
+		limiter.SetLimit(rate.Limit(limit.Rate))
+		limiter.SetBurst(limit.Burst)
+	}
+	return limiter.Allow()
+}
+
+// pruneLimiters drops the buckets that refilled completely, they are recreated full on demand.
+func (b *Backend) pruneLimiters() {
+	b.limitersLock.Lock()
+	defer b.limitersLock.Unlock()
+
+	for key, limiter := range b.limiters {
+		if limiter.Tokens() >= float64(limiter.Burst()) {
+			delete(b.limiters, key)
+		}
+	}
+}
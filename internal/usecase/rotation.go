@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	keyStatusActive  = "active"
+	keyStatusRetired = "retired"
+)
+
+// status reports whether the key pair is active, expired or was retired by a rotation.
+func (k *KeyPair) status() string {
+	if k.expired(time.Now()) {
+		return keyStatusExpired
+	}
+	if k.RetiredAt != nil {
+		return keyStatusRetired
+	}
+	if k.Pooled {
+		return keyStatusPooled
+	}
+	return keyStatusActive
+}
+
+// info is the per-key summary returned when reading a key-manager.
+func (k *KeyPair) info() map[string]interface{} {
+	info := map[string]interface{}{
+		"status":     k.status(),
+		"public_key": k.PublicKey,
+	}
+	if k.DerivationPath != "" {
+		info["derivation_path"] = k.DerivationPath
+	}
+	if k.RetiredAt != nil {
+		info["retired_at"] = k.RetiredAt.Format(time.RFC3339)
+	}
+	if k.Successor != "" {
+		info["successor"] = k.Successor
+	}
+	if k.Predecessor != "" {
+		info["predecessor"] = k.Predecessor
+	}
+	if k.Session {
+		info["session"] = true
+	}
+	if len(k.Labels) > 0 {
+		info["labels"] = k.Labels
+	}
+	if k.NotAfter != nil {
+		info["not_after"] = k.NotAfter.Format(time.RFC3339)
+	}
+	if k.MaxSignatures > 0 {
+		info["max_signatures"] = k.MaxSignatures
+		info["signatures"] = k.Signatures
+	}
+	return info
+}
+
+// checkSigningKey rejects signing with an expired key pair, and with a retired key pair unless the
+// key-manager allows it, e.g. to sweep the funds left on the old address.
+func (k *KeyManager) checkSigningKey(keyPair *KeyPair) error {
+	if err := keyPair.checkExpiry(time.Now()); err != nil {
+		return err
+	}
+	if keyPair.Pooled {
+		return fmt.Errorf("address %s has not been claimed from the key pool", keyPair.Address)
+	}
+	if keyPair.RetiredAt != nil && !k.AllowRetiredSigning {
+		return fmt.Errorf("address %s was retired and succeeded by %s, set allowRetiredSigning to sign with it",
+			keyPair.Address, keyPair.Successor)
+	}
+	return nil
+}
+
+// rotate retires the key pair and appends its successor, generated like any new key of the
+// key-manager.
+func (k *KeyManager) rotate(keyPair *KeyPair) (*KeyPair, error) {
+	if keyPair.Session {
+		return nil, fmt.Errorf("address %s is a session key and cannot be rotated", keyPair.Address)
+	}
+	if keyPair.RetiredAt != nil {
+		return nil, fmt.Errorf("address %s was already retired and succeeded by %s", keyPair.Address, keyPair.Successor)
+	}
+
+	if keyPair.Pooled {
+		return nil, fmt.Errorf("address %s has not been claimed from the key pool", keyPair.Address)
+	}
+
+	successor, err := k.generateKeyPair()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	keyPair.RetiredAt = &now
+	keyPair.Successor = successor.Address
+	successor.Predecessor = keyPair.Address
+
+	k.KeyPairs = append(k.KeyPairs, successor)
+	return successor, nil
+}
@@ -0,0 +1,124 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	secretTypeSessionKey = "session_key"
+
+	defaultSessionKeyTTL    = time.Hour
+	defaultSessionKeyMaxTTL = 24 * time.Hour
+)
+
+// secretSessionKey is the lease of a session key. Revoking the lease, or letting it expire,
+// destroys the key.
+func secretSessionKey(b *Backend) *framework.Secret {
+	return &framework.Secret{
+		Type: secretTypeSessionKey,
+		Fields: map[string]*framework.FieldSchema{
+			"service_name": {
+				Type:        framework.TypeString,
+				Description: "The key-manager of the session key.",
+			},
+			"address": {
+				Type:        framework.TypeString,
+				Description: "The address of the session key.",
+			},
+		},
+		DefaultDuration: defaultSessionKeyTTL,
+		Renew:           b.renewSessionKey,
+		Revoke:          b.revokeSessionKey,
+	}
+}
+
+func (b *Backend) renewSessionKey(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	serviceName, address, err := sessionKeyLease(req)
+	if err != nil {
+		return nil, err
+	}
+
+	keyManager, err := b.retrieveKeyManager(ctx, req, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	var keyPair *KeyPair
+	if keyManager != nil {
+		keyPair = keyManager.keyPair(address)
+	}
+	if keyPair == nil {
+		return nil, fmt.Errorf("session key %s of keyManager %s no longer exists", address, serviceName)
+	}
+	if err = keyPair.checkExpiry(time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{Secret: req.Secret}, nil
+}
+
+func (b *Backend) revokeSessionKey(
+	ctx context.Context,
+	req *logical.Request,
+	data *framework.FieldData,
+) (*logical.Response, error) {
+	serviceName, address, err := sessionKeyLease(req)
+	if err != nil {
+		return nil, err
+	}
+
+	b.keyManagerLock.Lock()
+	defer b.keyManagerLock.Unlock()
+
+	keyManager, err := b.retrieveKeyManager(ctx, req, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	// the key-manager may have been deleted together with its keys
+	if keyManager == nil {
+		return nil, nil
+	}
+
+	for i, keyPair := range keyManager.KeyPairs {
+		if keyPair.Address == address && keyPair.Session {
+			keyManager.KeyPairs = append(keyManager.KeyPairs[:i], keyManager.KeyPairs[i+1:]...)
+			if err = b.saveKeyManager(ctx, req, keyManager); err != nil {
+				return nil, err
+			}
+			b.Logger().Info("Session key destroyed", "service_name", serviceName, "address", address)
+			break
+		}
+	}
+	return nil, nil
+}
+
+func sessionKeyLease(req *logical.Request) (string, string, error) {
+	serviceName, ok := req.Secret.InternalData["service_name"].(string)
+	if !ok {
+		return "", "", fmt.Errorf("session key lease is missing the service_name")
+	}
+	address, ok := req.Secret.InternalData["address"].(string)
+	if !ok {
+		return "", "", fmt.Errorf("session key lease is missing the address")
+	}
+	return serviceName, address, nil
+}
+
+// durableKeyPairs returns the key pairs of the key-manager without its session keys.
+func (k *KeyManager) durableKeyPairs() []*KeyPair {
+	keyPairs := make([]*KeyPair, 0, len(k.KeyPairs))
+	for _, keyPair := range k.KeyPairs {
+		if !keyPair.Session {
+			keyPairs = append(keyPairs, keyPair)
+		}
+	}
+	return keyPairs
+}
@@ -65,6 +65,12 @@ func zeroKey(k *ecdsa.PrivateKey) {
 	}
 }
 
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
 func validNumber(input string) *big.Int {
 	if input == "" {
 		return big.NewInt(0)
@@ -89,3 +95,12 @@ func contains(arr []*big.Int, value *big.Int) bool {
 	}
 	return false
 }
+
+func containsString(arr []string, value string) bool {
+	for _, a := range arr {
+		if a == value {
+			return true
+		}
+	}
+	return false
+}
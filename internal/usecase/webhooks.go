@@ -0,0 +1,252 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	webhookOutboxPath      = "webhooks/outbox/"
+	webhookEventType       = "signing.decision"
+	webhookSignatureHeader = "X-Eth-Signer-Signature"
+	webhookEventIDHeader   = "X-Eth-Signer-Event-Id"
+	maxWebhookAttempts     = 10
+)
+
+var (
+	// webhookRetryBackoff is the delay before the first retry, doubled on every further attempt.
+	webhookRetryBackoff = 5 * time.Second
+	maxWebhookBackoff   = time.Hour
+	webhookTimeout      = 10 * time.Second
+)
+
+// WebhookEvent is the payload posted to the configured webhooks.
+type WebhookEvent struct {
+	ID     string       `json:"id"`
+	Type   string       `json:"type"`
+	Time   time.Time    `json:"time"`
+	Record *AuditRecord `json:"record"`
+}
+
+// WebhookDelivery is an event in the outbox that still has to be delivered to some webhooks.
+type WebhookDelivery struct {
+	Event       *WebhookEvent `json:"event"`
+	URLs        []string      `json:"urls"`
+	Attempts    int           `json:"attempts"`
+	NextAttempt time.Time     `json:"next_attempt"`
+	LastError   string        `json:"last_error,omitempty"`
+}
+
+// enqueueWebhooks stores the signing decision in the outbox, which the periodic function delivers.
+// Performance standbys and secondaries leave delivery to the active node of the primary cluster.
+func (b *Backend) enqueueWebhooks(ctx context.Context, storage logical.Storage, record *AuditRecord) error {
+	if !b.WriteSafeReplicationState() {
+		return nil
+	}
+
+	config, err := b.retrieveConfigFromStorage(ctx, storage)
+	if err != nil {
+		return err
+	}
+	// records that failed to get an ID were not persisted either
+	if len(config.WebhookURLs) == 0 || record.ID == "" {
+		return nil
+	}
+
+	delivery := &WebhookDelivery{
+		Event: &WebhookEvent{
+			ID:     record.ID,
+			Type:   webhookEventType,
+			Time:   record.Time,
+			Record: record,
+		},
+		URLs:        config.WebhookURLs,
+		NextAttempt: time.Now().UTC(),
+	}
+	return saveWebhookDelivery(ctx, storage, delivery)
+}
+
+// flushWebhooks attempts every outbox delivery that is due. Deliveries are removed once every
+// webhook accepted the event or the attempts are exhausted.
+func (b *Backend) flushWebhooks(ctx context.Context, storage logical.Storage) error {
+	config, err := b.retrieveConfigFromStorage(ctx, storage)
+	if err != nil {
+		return err
+	}
+
+	due, err := b.claimWebhookDeliveries(ctx, storage, time.Now())
+	if err != nil {
+		return err
+	}
+	defer b.releaseWebhookDeliveries(due)
+
+	// the webhooks are called without holding webhookLock, the claim keeps a concurrent flush
+	// from delivering the same events
+	for _, delivery := range due {
+		b.attemptDelivery(ctx, config.WebhookSecret, delivery)
+
+		id := delivery.Event.ID
+		switch {
+		case len(delivery.URLs) == 0:
+			err = storage.Delete(ctx, webhookOutboxPath+id)
+		case delivery.Attempts >= maxWebhookAttempts:
+			b.Logger().Error("Giving up delivering webhook event", "id", id, "urls", delivery.URLs,
+				"attempts", delivery.Attempts, "error", delivery.LastError)
+			err = storage.Delete(ctx, webhookOutboxPath+id)
+		default:
+			err = saveWebhookDelivery(ctx, storage, delivery)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claimWebhookDeliveries returns the outbox deliveries that are due and not already being
+// delivered, and marks them as in flight.
+func (b *Backend) claimWebhookDeliveries(
+	ctx context.Context,
+	storage logical.Storage,
+	now time.Time,
+) ([]*WebhookDelivery, error) {
+	b.webhookLock.Lock()
+	defer b.webhookLock.Unlock()
+
+	ids, err := storage.List(ctx, webhookOutboxPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.webhooksInFlight == nil {
+		b.webhooksInFlight = make(map[string]bool)
+	}
+
+	var due []*WebhookDelivery
+	for _, id := range ids {
+		if b.webhooksInFlight[id] {
+			continue
+		}
+
+		entry, err := storage.Get(ctx, webhookOutboxPath+id)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			continue
+		}
+
+		var delivery WebhookDelivery
+		if err = entry.DecodeJSON(&delivery); err != nil {
+			b.Logger().Error("Failed to decode webhook delivery", "id", id, "error", err)
+			continue
+		}
+		if now.Before(delivery.NextAttempt) {
+			continue
+		}
+
+		b.webhooksInFlight[id] = true
+		due = append(due, &delivery)
+	}
+	return due, nil
+}
+
+func (b *Backend) releaseWebhookDeliveries(deliveries []*WebhookDelivery) {
+	b.webhookLock.Lock()
+	defer b.webhookLock.Unlock()
+
+	for _, delivery := range deliveries {
+		delete(b.webhooksInFlight, delivery.Event.ID)
+	}
+}
+
+// attemptDelivery posts the event to the webhooks that have not accepted it yet and keeps the
+// ones that failed for the next attempt.
+func (b *Backend) attemptDelivery(ctx context.Context, secret string, delivery *WebhookDelivery) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		delivery.URLs = nil
+		b.Logger().Error("Failed to encode webhook event", "id", delivery.Event.ID, "error", err)
+		return
+	}
+
+	var failed []string
+	for _, target := range delivery.URLs {
+		if err = b.postWebhook(ctx, target, secret, delivery.Event.ID, body); err != nil {
+			b.Logger().Warn("Webhook delivery failed", "id", delivery.Event.ID, "url", target, "error", err)
+			delivery.LastError = err.Error()
+			failed = append(failed, target)
+		}
+	}
+
+	delivery.URLs = failed
+	delivery.Attempts++
+
+	backoff := webhookRetryBackoff << (delivery.Attempts - 1)
+	if backoff > maxWebhookBackoff || backoff < 0 {
+		backoff = maxWebhookBackoff
+	}
+	delivery.NextAttempt = time.Now().UTC().Add(backoff)
+}
+
+func (b *Backend) postWebhook(ctx context.Context, target, secret, eventID string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(webhookEventIDHeader, eventID)
+	if secret != "" {
+		httpReq.Header.Set(webhookSignatureHeader, "sha256="+webhookSignature(secret, body))
+	}
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// webhookSignature is the hex encoded HMAC-SHA256 of the payload, which receivers recompute with
+// the shared secret to authenticate the event.
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func saveWebhookDelivery(ctx context.Context, storage logical.Storage, delivery *WebhookDelivery) error {
+	entry, _ := logical.StorageEntryJSON(webhookOutboxPath+delivery.Event.ID, delivery)
+	return storage.Put(ctx, entry)
+}
+
+// parseWebhookURLs validates that every webhook is an absolute http(s) URL.
+func parseWebhookURLs(input []string) ([]string, error) {
+	urls := make([]string, 0, len(input))
+	for _, raw := range input {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid webhook URL %q", raw)
+		}
+		urls = append(urls, raw)
+	}
+	return urls, nil
+}
@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const wrappingKeyPath = "wrapping_key"
+
+// wrappingKeyBits is the size of the RSA wrapping key, as used by Vault transit.
+var wrappingKeyBits = 4096
+
+// WrappingKey is the mount-specific RSA key that keys are encrypted to before they are imported.
+type WrappingKey struct {
+	PrivateKey string `json:"private_key"`
+}
+
+// retrieveWrappingKey returns the wrapping key of the mount, generating it on first use.
+func (b *Backend) retrieveWrappingKey(ctx context.Context, req *logical.Request) (*rsa.PrivateKey, error) {
+	return b.retrieveMountKey(ctx, req, wrappingKeyPath)
+}
+
+// retrieveMountKey returns the RSA key of the mount stored at path, generating it on first use.
+func (b *Backend) retrieveMountKey(ctx context.Context, req *logical.Request, path string) (*rsa.PrivateKey, error) {
+	b.wrappingKeyLock.Lock()
+	defer b.wrappingKeyLock.Unlock()
+
+	entry, err := req.Storage.Get(ctx, path)
+	if err != nil {
+		b.Logger().Error("Failed to retrieve the mount key", "path", path, "error", err)
+		return nil, err
+	}
+
+	if entry != nil {
+		var wrappingKey WrappingKey
+		if err = entry.DecodeJSON(&wrappingKey); err != nil {
+			b.Logger().Error("Failed to decode the mount key", "path", path, "error", err)
+			return nil, err
+		}
+		der, err := base64.StdEncoding.DecodeString(wrappingKey.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		return x509.ParsePKCS1PrivateKey(der)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, wrappingKeyBits)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, _ = logical.StorageEntryJSON(path, &WrappingKey{
+		PrivateKey: base64.StdEncoding.EncodeToString(x509.MarshalPKCS1PrivateKey(key)),
+	})
+	if err = req.Storage.Put(ctx, entry); err != nil {
+		b.Logger().Error("Failed to save the mount key to storage", "path", path, "error", err)
+		return nil, err
+	}
+
+	b.Logger().Info("Generated a key of the mount", "path", path, "bits", wrappingKeyBits)
+	return key, nil
+}
+
+// unwrap decrypts a base64 encoded RSA-OAEP (SHA-256) ciphertext encrypted to the wrapping key.
+func (b *Backend) unwrap(ctx context.Context, req *logical.Request, ciphertext string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, errors.New("ciphertext must be base64 encoded")
+	}
+
+	wrappingKey, err := b.retrieveWrappingKey(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := rsa.DecryptOAEP(sha256.New(), nil, wrappingKey, raw, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap the ciphertext with the wrapping key")
+	}
+	return plaintext, nil
+}
+
+func encodePublicKeyPEM(key *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}